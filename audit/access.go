@@ -0,0 +1,175 @@
+// audit/access.go
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ErrAccessDenied is returned when a principal is not allowed to read the requested audit events
+var ErrAccessDenied = errors.New("access to audit events denied")
+
+// Role determines which organizations' audit events a principal may read
+type Role string
+
+const (
+	// RoleOrgAdmin reads the audit events of its own organization
+	RoleOrgAdmin Role = "org-admin"
+	// RoleAuditor reads the audit events of its assigned organizations
+	RoleAuditor Role = "auditor"
+	// RoleSuperAdmin reads the audit events of every organization
+	RoleSuperAdmin Role = "super-admin"
+)
+
+// Principal identifies who is reading audit events
+type Principal struct {
+	Username string
+	Role     Role
+
+	// OrgID is the organization of an org admin
+	OrgID int64
+
+	// AssignedOrgs are the organizations an auditor may read
+	AssignedOrgs []int64
+}
+
+// ReadAuthorizer decides whether a principal may read the audit events of an organization.
+// It returns nil to allow the read, or an error wrapping ErrAccessDenied to deny it.
+type ReadAuthorizer interface {
+	AuthorizeRead(principal Principal, orgID int64) error
+}
+
+// ReadAuthorizerFunc adapts a function to the ReadAuthorizer interface
+type ReadAuthorizerFunc func(principal Principal, orgID int64) error
+
+// AuthorizeRead calls f
+func (f ReadAuthorizerFunc) AuthorizeRead(principal Principal, orgID int64) error {
+	return f(principal, orgID)
+}
+
+// RolePolicy is the default ReadAuthorizer: org admins read their organization,
+// auditors read their assigned organizations and super admins read everything
+type RolePolicy struct{}
+
+// AuthorizeRead applies the role rules
+func (RolePolicy) AuthorizeRead(principal Principal, orgID int64) error {
+	switch principal.Role {
+	case RoleSuperAdmin:
+		return nil
+	case RoleOrgAdmin:
+		if principal.OrgID == orgID {
+			return nil
+		}
+	case RoleAuditor:
+		if slices.Contains(principal.AssignedOrgs, orgID) {
+			return nil
+		}
+	default:
+		return fmt.Errorf("%w: %q has unknown role %q", ErrAccessDenied, principal.Username, principal.Role)
+	}
+	return fmt.Errorf("%w: %s %q may not read org %d", ErrAccessDenied, principal.Role, principal.Username, orgID)
+}
+
+// AuthorizedReader checks every read of Logger against Authorizer and records each
+// read, allowed, denied or failed, as an audit event in the organization that was read
+type AuthorizedReader struct {
+	Logger AuditLogger
+
+	// Authorizer defaults to RolePolicy
+	Authorizer ReadAuthorizer
+
+	// AccessLog receives the events recording each read and is required. Use a
+	// separate ProtectedAuditLogger so access records cannot be purged.
+	AccessLog AuditLogger
+
+	// Archiver, when set, lets QueryAuditEvents include archived events
+	Archiver *Archiver
+
+	// Pseudonymizer, when set, is applied to every export
+	Pseudonymizer *Pseudonymizer
+}
+
+// ReadAuditEvents reads the events of orgID on behalf of principal. Reads that are
+// denied, or that cannot be recorded, return an error and no events.
+func (r *AuthorizedReader) ReadAuditEvents(principal Principal, orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	filters := map[string]interface{}{"startSec": startEpochSec, "endSec": endEpochSec}
+	return r.access(principal, "read", ActionAuditEventsRead, orgID, filters, func() ([]AuditEvent, error) {
+		return r.Logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+	})
+}
+
+// QueryAuditEvents reads the events of orgID on behalf of principal like ReadAuditEvents,
+// also searching the archive when includeArchived is set
+func (r *AuthorizedReader) QueryAuditEvents(principal Principal, orgID int64, startEpochSec, endEpochSec int64, includeArchived bool) ([]AuditEvent, error) {
+	filters := map[string]interface{}{"startSec": startEpochSec, "endSec": endEpochSec, "includeArchived": includeArchived}
+	return r.access(principal, "query", ActionAuditEventsRead, orgID, filters, func() ([]AuditEvent, error) {
+		if includeArchived {
+			if r.Archiver == nil {
+				return nil, fmt.Errorf("no archiver configured for archived audit events")
+			}
+			return r.Archiver.ReadAuditEvents(orgID, startEpochSec, endEpochSec, true)
+		}
+		return r.Logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+	})
+}
+
+// ExportAuditEvents writes the events of orgID to w as JSON lines on behalf of principal,
+// pseudonymized when Pseudonymizer is set. Nothing is written unless the export is recorded.
+func (r *AuthorizedReader) ExportAuditEvents(principal Principal, w io.Writer, orgID int64, startEpochSec, endEpochSec int64) error {
+	filters := map[string]interface{}{"startSec": startEpochSec, "endSec": endEpochSec, "pseudonymized": r.Pseudonymizer != nil}
+	events, err := r.access(principal, "export", ActionAuditEventsExported, orgID, filters, func() ([]AuditEvent, error) {
+		if r.Pseudonymizer != nil {
+			return r.Pseudonymizer.ReadAuditEvents(r.Logger, orgID, startEpochSec, endEpochSec)
+		}
+		return r.Logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+	})
+	if err != nil {
+		return err
+	}
+	return writeEvents(w, events)
+}
+
+// access authorizes principal, runs read and records the outcome in the access log
+func (r *AuthorizedReader) access(principal Principal, operation, action string, orgID int64, filters map[string]interface{}, read func() ([]AuditEvent, error)) ([]AuditEvent, error) {
+	authorizer := r.Authorizer
+	if authorizer == nil {
+		authorizer = RolePolicy{}
+	}
+	accessLog := r.AccessLog
+	if accessLog == nil {
+		return nil, fmt.Errorf("audit reader has no access log to record reads in")
+	}
+
+	metadata := map[string]interface{}{
+		"role":      principal.Role,
+		"operation": operation,
+		"orgId":     orgID,
+		"filters":   filters,
+	}
+
+	if err := authorizer.AuthorizeRead(principal, orgID); err != nil {
+		metadata["reason"] = err.Error()
+		if recordErr := accessLog.CreateAuditEvent(principal.Username, ActionAuditEventsReadDenied, "", 0, orgID, metadata); recordErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to record denied audit read: %v", recordErr))
+		}
+		return nil, err
+	}
+
+	events, err := read()
+	if err != nil {
+		metadata["error"] = err.Error()
+		if recordErr := accessLog.CreateAuditEvent(principal.Username, ActionAuditEventsReadFailed, "", 0, orgID, metadata); recordErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to record failed audit read: %v", recordErr))
+		}
+		return nil, err
+	}
+
+	// A read that cannot be recorded is not returned
+	metadata["results"] = len(events)
+	if err := accessLog.CreateAuditEvent(principal.Username, action, "", 0, orgID, metadata); err != nil {
+		return nil, fmt.Errorf("failed to record audit read: %v", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,91 @@
+// audit/access_test.go
+package audit_test
+
+import (
+	"errors"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestAuthorizedReader(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 100, 1, nil)
+	logger.CreateAuditEvent("bob", audit.ActionUserLogin, "", 100, 2, nil)
+	accessLog := audit.NewMemoryAuditLogger(0)
+	reader := &audit.AuthorizedReader{Logger: logger, AccessLog: audit.NewProtectedAuditLogger(accessLog)}
+
+	admin := audit.Principal{Username: "admin1", Role: audit.RoleOrgAdmin, OrgID: 1}
+	auditor := audit.Principal{Username: "auditor", Role: audit.RoleAuditor, AssignedOrgs: []int64{2}}
+	root := audit.Principal{Username: "root", Role: audit.RoleSuperAdmin}
+
+	tests := []struct {
+		principal audit.Principal
+		orgID     int64
+		allowed   bool
+	}{
+		{admin, 1, true},
+		{admin, 2, false},
+		{auditor, 2, true},
+		{auditor, 1, false},
+		{root, 1, true},
+		{root, 2, true},
+		{audit.Principal{Username: "nobody"}, 1, false},
+	}
+	for _, tt := range tests {
+		events, err := reader.ReadAuditEvents(tt.principal, tt.orgID, 0, 200)
+		if tt.allowed {
+			if err != nil || len(events) != 1 {
+				t.Errorf("%s reading org %d: expected 1 event, got %d (%v)", tt.principal.Username, tt.orgID, len(events), err)
+			}
+			audittest.ExpectEvent(t, accessLog, audittest.Match{Username: tt.principal.Username, ActionString: audit.ActionAuditEventsRead, OrgID: tt.orgID})
+			continue
+		}
+		if !errors.Is(err, audit.ErrAccessDenied) || events != nil {
+			t.Errorf("%s reading org %d: expected ErrAccessDenied, got %v", tt.principal.Username, tt.orgID, err)
+		}
+		audittest.ExpectEvent(t, accessLog, audittest.Match{Username: tt.principal.Username, ActionString: audit.ActionAuditEventsReadDenied, OrgID: tt.orgID})
+	}
+
+	// Reads that fail are recorded with the error
+	logger.Close()
+	if _, err := reader.ReadAuditEvents(root, 1, 0, 0); !errors.Is(err, audit.ErrLoggerClosed) {
+		t.Fatalf("Expected ErrLoggerClosed, got %v", err)
+	}
+	failed := audittest.ExpectEvent(t, accessLog, audittest.Match{Username: "root", ActionString: audit.ActionAuditEventsReadFailed, OrgID: 1})
+	if failed.Metadata.(map[string]interface{})["error"] == nil {
+		t.Errorf("Expected the failure to be recorded, got %+v", failed.Metadata)
+	}
+
+	// Without an access log nothing is read
+	unrecorded := &audit.AuthorizedReader{Logger: audit.NewMemoryAuditLogger(0)}
+	if _, err := unrecorded.ReadAuditEvents(root, 1, 0, 0); err == nil {
+		t.Error("Expected a reader without an access log to refuse reads")
+	}
+}
+
+func TestAuthorizedReaderCustomPolicy(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	reader := &audit.AuthorizedReader{
+		Logger:    logger,
+		AccessLog: audit.NewProtectedAuditLogger(audit.NewMemoryAuditLogger(0)),
+		Authorizer: audit.ReadAuthorizerFunc(func(principal audit.Principal, orgID int64) error {
+			if principal.Username != "compliance" {
+				return audit.ErrAccessDenied
+			}
+			return nil
+		}),
+	}
+
+	if _, err := reader.ReadAuditEvents(audit.Principal{Username: "root", Role: audit.RoleSuperAdmin}, 1, 0, 0); !errors.Is(err, audit.ErrAccessDenied) {
+		t.Errorf("Expected custom policy to deny, got %v", err)
+	}
+	if _, err := reader.ReadAuditEvents(audit.Principal{Username: "compliance"}, 1, 0, 0); err != nil {
+		t.Errorf("Expected custom policy to allow, got %v", err)
+	}
+}
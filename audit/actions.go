@@ -44,4 +44,16 @@ const (
 	// Lookup files
 	ActionLookupFileCreate = "Lookup file created"
 	ActionLookupFileDelete = "Lookup file deleted"
+	
+	// Audit log maintenance
+	ActionAuditEventsPurged = "Audit events purged"
+	ActionLegalHoldPlace    = "Legal hold placed"
+	ActionLegalHoldRelease  = "Legal hold released"
+	ActionUserDataErased    = "User personal data erased"
+	
+	// Audit log access
+	ActionAuditEventsRead       = "Audit events read"
+	ActionAuditEventsReadDenied = "Audit events read denied"
+	ActionAuditEventsReadFailed = "Audit events read failed"
+	ActionAuditEventsExported   = "Audit events exported"
 )
\ No newline at end of file
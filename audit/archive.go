@@ -0,0 +1,492 @@
+// audit/archive.go
+package audit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrArchiveCorrupt is returned when an archive file does not match its checksum
+var ErrArchiveCorrupt = errors.New("audit archive checksum mismatch")
+
+// Archive file names and the gzip header comment carrying their checksum
+const (
+	archiveSuffix          = ".jsonl.gz"
+	encryptedArchiveSuffix = ".enc"
+	archiveChecksumPrefix  = "sha256:"
+)
+
+// AuditScanner is implemented by loggers that can iterate over every stored event
+type AuditScanner interface {
+	ScanAuditEvents(fn func(AuditEvent) error) error
+}
+
+// scanWrapped scans a logger wrapped by a decorator, failing if it cannot scan
+func scanWrapped(inner AuditLogger, fn func(AuditEvent) error) error {
+	scanner, ok := inner.(AuditScanner)
+	if !ok {
+		return fmt.Errorf("audit logger %T does not support scanning", inner)
+	}
+	return scanner.ScanAuditEvents(fn)
+}
+
+// storedScanner is implemented by wrappers that store events in a different form from
+// the one their reads return. Archives keep the stored form, so data a wrapper seals
+// never reaches an archive file in the clear.
+type storedScanner interface {
+	// scanStored calls fn for every event as it is stored
+	scanStored(fn func(AuditEvent) error) error
+
+	// openEvent returns a stored event in the form reads return
+	openEvent(event AuditEvent) AuditEvent
+}
+
+// scanStored scans logger, passing events in their stored form
+func scanStored(logger AuditLogger, fn func(AuditEvent) error) error {
+	if scanner, ok := logger.(storedScanner); ok {
+		return scanner.scanStored(fn)
+	}
+	return scanWrapped(logger, fn)
+}
+
+// openEvent returns an event stored by logger in the form its reads return
+func openEvent(logger AuditLogger, event AuditEvent) AuditEvent {
+	if scanner, ok := logger.(storedScanner); ok {
+		return scanner.openEvent(event)
+	}
+	return event
+}
+
+// Archiver moves events older than a threshold out of a live logger into
+// compressed, checksummed files laid out as <Dir>/org-<orgID>/<YYYY-MM>.jsonl.gz,
+// with a .enc suffix when they are encrypted
+type Archiver struct {
+	// Logger must implement AuditScanner and AuditPurger
+	Logger AuditLogger
+
+	// Dir is the root directory of the archive
+	Dir string
+
+	// OlderThan is the minimum age of archived events
+	OlderThan time.Duration
+
+	// Holds, when set, keeps events under legal hold in the live logger
+	Holds *LegalHoldRegistry
+
+	// Keys encrypts archive files; it defaults to the key ring of Logger, so
+	// events from an encrypted logger are never archived in plaintext
+	Keys *KeyRing
+
+	// Interval between runs of Run
+	Interval time.Duration
+
+	// OnError is called when a run of Run fails
+	OnError func(error)
+}
+
+// archiveKey identifies a single archive file
+type archiveKey struct {
+	orgID int64
+	month string
+}
+
+// ArchiveOnce archives events older than OlderThan at now and returns the number archived per organization
+func (a *Archiver) ArchiveOnce(now time.Time) (map[int64]int, error) {
+	if a.OlderThan <= 0 {
+		return nil, fmt.Errorf("archive threshold must be positive, got %s", a.OlderThan)
+	}
+	if isProtected(a.Logger) {
+		return nil, ErrProtectedStream
+	}
+	// Held events stay live: both the logger's own holds and those of a.Holds
+	holds, err := legalHoldsOf(a.Logger)
+	if err != nil {
+		return nil, err
+	}
+	if a.Holds != nil {
+		holds = append(holds, a.Holds.Holds()...)
+	}
+
+	cutoff := now.Add(-a.OlderThan).Unix()
+	batches := make(map[archiveKey][]AuditEvent)
+	archived := make(map[string]bool)
+	err = scanStored(a.Logger, func(stored AuditEvent) error {
+		// Holds and the purge below see events as reads return them
+		event := openEvent(a.Logger, stored)
+		if event.EpochTimestampSec >= cutoff || holdsCover(holds, event) {
+			return nil
+		}
+		key := archiveKey{orgID: event.OrgID, month: archiveMonth(event.EpochTimestampSec)}
+		batches[key] = append(batches[key], stored)
+
+		identity, err := eventIdentity(event)
+		if err != nil {
+			return err
+		}
+		archived[identity] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan audit events: %w", err)
+	}
+
+	// Write every archive file before deleting anything from the live logger
+	keys := a.keys()
+	for key, events := range batches {
+		if err := a.appendArchive(keys, key, events); err != nil {
+			return nil, err
+		}
+	}
+
+	deleted, err := PurgeAuditEvents(a.Logger, a.Holds, func(event AuditEvent) bool {
+		identity, err := eventIdentity(event)
+		return err == nil && event.EpochTimestampSec < cutoff && archived[identity]
+	})
+	if err != nil {
+		return nil, fmt.Errorf("archived events but failed to remove them from the live log: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Run archives old events every Interval until ctx is done
+func (a *Archiver) Run(ctx context.Context) error {
+	if a.Interval <= 0 {
+		return fmt.Errorf("archiver interval must be positive, got %s", a.Interval)
+	}
+
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := a.ArchiveOnce(time.Now()); err != nil && a.OnError != nil {
+			a.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReadAuditEvents reads events for an organization and time range from the live logger,
+// also including archived events when includeArchived is true. It is a privileged path
+// that is neither authorized nor recorded; use AuthorizedReader.QueryAuditEvents for users.
+func (a *Archiver) ReadAuditEvents(orgID int64, startEpochSec, endEpochSec int64, includeArchived bool) ([]AuditEvent, error) {
+	events, err := a.Logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+	if err != nil || !includeArchived {
+		return events, err
+	}
+
+	archivedEvents, err := ReadEncryptedArchivedAuditEvents(a.Dir, a.keys(), orgID, startEpochSec, endEpochSec)
+	if err != nil {
+		return nil, err
+	}
+	for i := range archivedEvents {
+		archivedEvents[i] = openEvent(a.Logger, archivedEvents[i])
+	}
+
+	// Archived events are older than anything still live for the same time
+	events = append(archivedEvents, events...)
+	sortEvents(events)
+	return events, nil
+}
+
+// keys returns the key ring archive files are encrypted with, or nil for plaintext archives
+func (a *Archiver) keys() *KeyRing {
+	if a.Keys != nil {
+		return a.Keys
+	}
+	return keyRingOf(a.Logger)
+}
+
+// ReadArchivedAuditEvents reads events for an organization and time range from an
+// unencrypted archive in dir. Like ReadEncryptedArchivedAuditEvents, it is not authorized
+// or recorded.
+func ReadArchivedAuditEvents(dir string, orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	return ReadEncryptedArchivedAuditEvents(dir, nil, orgID, startEpochSec, endEpochSec)
+}
+
+// ReadEncryptedArchivedAuditEvents reads events for an organization and time range from
+// the archive in dir, decrypting archive files with keys
+func ReadEncryptedArchivedAuditEvents(dir string, keys *KeyRing, orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	orgDir := filepath.Join(dir, fmt.Sprintf("org-%d", orgID))
+	entries, err := os.ReadDir(orgDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit archive: %v", err)
+	}
+
+	firstMonth := archiveMonth(startEpochSec)
+	lastMonth := ""
+	if endEpochSec != 0 {
+		lastMonth = archiveMonth(endEpochSec)
+	}
+
+	// A month can briefly have both a plaintext and an encrypted file
+	months := make(map[string]bool)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), encryptedArchiveSuffix)
+		month, ok := strings.CutSuffix(name, archiveSuffix)
+		if !ok || month < firstMonth || (lastMonth != "" && month > lastMonth) {
+			continue
+		}
+		months[month] = true
+	}
+
+	var events []AuditEvent
+	for month := range months {
+		monthEvents, err := readArchiveMonth(keys, archiveKey{orgID: orgID, month: month}, orgDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range monthEvents {
+			if event.EpochTimestampSec >= startEpochSec &&
+				(endEpochSec == 0 || event.EpochTimestampSec <= endEpochSec) {
+				events = append(events, event)
+			}
+		}
+	}
+
+	sortEvents(events)
+	return events, nil
+}
+
+// appendArchive merges events into the archive file for key, encrypting it when keys is set.
+// Events already in the file are not added again, so rerunning after a failed purge is safe.
+func (a *Archiver) appendArchive(keys *KeyRing, key archiveKey, events []AuditEvent) error {
+	orgDir := filepath.Join(a.Dir, fmt.Sprintf("org-%d", key.orgID))
+	if err := os.MkdirAll(orgDir, 0755); err != nil {
+		return fmt.Errorf("failed to create audit archive directory: %v", err)
+	}
+
+	existing, err := readArchiveMonth(keys, key, orgDir)
+	if err != nil {
+		return err
+	}
+	merged, err := mergeArchived(existing, events)
+	if err != nil {
+		return err
+	}
+
+	data, err := encodeArchive(merged)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(orgDir, key.month+archiveSuffix)
+	plainPath := path
+	if keys != nil {
+		record, err := keys.encrypt(archivePurpose(key), data)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt audit archive: %v", err)
+		}
+		if data, err = json.Marshal(record); err != nil {
+			return fmt.Errorf("failed to marshal encrypted audit archive: %v", err)
+		}
+		path += encryptedArchiveSuffix
+	}
+
+	// The checksum is inside the file, so a single rename replaces the month
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return err
+	}
+
+	// The encrypted file holds every event of a plaintext file for the same month
+	if keys != nil {
+		if err := os.Remove(plainPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove superseded audit archive file: %v", err)
+		}
+	}
+	return nil
+}
+
+// mergeArchived adds events to existing, skipping each event already present as many
+// times as it appears in events
+func mergeArchived(existing, events []AuditEvent) ([]AuditEvent, error) {
+	counts := make(map[string]int, len(existing))
+	for _, event := range existing {
+		identity, err := eventIdentity(event)
+		if err != nil {
+			return nil, err
+		}
+		counts[identity]++
+	}
+
+	merged := existing
+	for _, event := range events {
+		identity, err := eventIdentity(event)
+		if err != nil {
+			return nil, err
+		}
+		if counts[identity] > 0 {
+			counts[identity]--
+			continue
+		}
+		merged = append(merged, event)
+	}
+	sortEvents(merged)
+	return merged, nil
+}
+
+// encodeArchive compresses events as JSON lines, storing the checksum of the
+// uncompressed content in the gzip header comment
+func encodeArchive(events []AuditEvent) ([]byte, error) {
+	var content bytes.Buffer
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal archived audit event: %v", err)
+		}
+		content.Write(append(line, '\n'))
+	}
+	sum := sha256.Sum256(content.Bytes())
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Comment = archiveChecksumPrefix + hex.EncodeToString(sum[:])
+	if _, err := writer.Write(content.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to compress audit archive: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress audit archive: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// readArchiveMonth reads every archive file of a month, returning no events if there are none
+func readArchiveMonth(keys *KeyRing, key archiveKey, orgDir string) ([]AuditEvent, error) {
+	path := filepath.Join(orgDir, key.month+archiveSuffix)
+	plain, err := readArchiveFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path + encryptedArchiveSuffix)
+	if os.IsNotExist(err) {
+		return plain, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit archive: %v", err)
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("%w: audit archive %s is encrypted but no key ring is configured", ErrRecordDecryption, path+encryptedArchiveSuffix)
+	}
+	var record encryptedRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrArchiveCorrupt, path+encryptedArchiveSuffix, err)
+	}
+	if data, err = keys.decrypt(archivePurpose(key), record); err != nil {
+		return nil, fmt.Errorf("%s: %w", path+encryptedArchiveSuffix, err)
+	}
+	encrypted, err := decodeArchive(path+encryptedArchiveSuffix, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// A crash while encrypting a plaintext month leaves both files behind
+	return mergeArchived(encrypted, plain)
+}
+
+// readArchiveFile verifies and decodes a single unencrypted archive file
+func readArchiveFile(path string) ([]AuditEvent, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read audit archive: %v", err)
+	}
+	return decodeArchive(path, data)
+}
+
+// decodeArchive verifies and decodes the compressed content of an archive file
+func decodeArchive(path string, data []byte) ([]AuditEvent, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrArchiveCorrupt, path, err)
+	}
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrArchiveCorrupt, path, err)
+	}
+
+	sum := sha256.Sum256(content)
+	expected, ok := strings.CutPrefix(reader.Comment, archiveChecksumPrefix)
+	if !ok || expected != hex.EncodeToString(sum[:]) {
+		return nil, fmt.Errorf("%w: %s", ErrArchiveCorrupt, path)
+	}
+
+	var events []AuditEvent
+	for _, line := range bytes.Split(content, []byte{'\n'}) {
+		if len(line) == 0 {
+			continue
+		}
+		var event AuditEvent
+		if err := decodeJSON(line, &event); err != nil {
+			return nil, fmt.Errorf("invalid event in audit archive %s: %v", path, err)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// archivePurpose binds an encrypted archive file to its organization and month
+func archivePurpose(key archiveKey) string {
+	return fmt.Sprintf("%s|org-%d/%s", purposeArchive, key.orgID, key.month)
+}
+
+// archiveMonth returns the UTC month of a timestamp as YYYY-MM
+func archiveMonth(epochSec int64) string {
+	return time.Unix(epochSec, 0).UTC().Format("2006-01")
+}
+
+// eventIdentity returns a key identifying an event by its full contents
+func eventIdentity(event AuditEvent) (string, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal audit event: %v", err)
+	}
+	return string(data), nil
+}
+
+// writeFileAtomic replaces path with data via a synced temporary file
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %v", path, err)
+	}
+	defer os.Remove(temp.Name())
+	defer temp.Close()
+
+	if _, err := temp.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	if err := temp.Chmod(perm); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %v", path, err)
+	}
+	if err := temp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %v", path, err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %v", path, err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %v", path, err)
+	}
+	return nil
+}
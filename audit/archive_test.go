@@ -0,0 +1,156 @@
+// audit/archive_test.go
+package audit_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"AuditEventsModule/audit"
+)
+
+func TestArchiver(t *testing.T) {
+	dir := t.TempDir()
+	logger, err := audit.NewDBAuditLogger(filepath.Join(dir, "audit.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DB audit logger: %v", err)
+	}
+	defer logger.Close()
+
+	now := time.Date(2025, time.June, 15, 0, 0, 0, 0, time.UTC)
+	jan := time.Date(2025, time.January, 10, 0, 0, 0, 0, time.UTC).Unix()
+	feb := time.Date(2025, time.February, 10, 0, 0, 0, 0, time.UTC).Unix()
+	logger.CreateAuditEvent("alice", audit.ActionIndexCreate, "jan", jan, 1, map[string]interface{}{"index": "logs"})
+	logger.CreateAuditEvent("alice", audit.ActionIndexCreate, "feb", feb, 1, nil)
+	logger.CreateAuditEvent("bob", audit.ActionIndexCreate, "jan", jan, 2, nil)
+	logger.CreateAuditEvent("alice", audit.ActionIndexCreate, "recent", now.Unix()-60, 1, nil)
+
+	archiver := &audit.Archiver{
+		Logger:    logger,
+		Dir:       filepath.Join(dir, "archive"),
+		OlderThan: 30 * 24 * time.Hour,
+	}
+	archived, err := archiver.ArchiveOnce(now)
+	if err != nil {
+		t.Fatalf("Archive run failed: %v", err)
+	}
+	if archived[1] != 2 || archived[2] != 1 {
+		t.Fatalf("Unexpected archive counts: %v", archived)
+	}
+
+	for _, name := range []string{"org-1/2025-01.jsonl.gz", "org-1/2025-02.jsonl.gz", "org-2/2025-01.jsonl.gz"} {
+		if _, err := os.Stat(filepath.Join(archiver.Dir, name)); err != nil {
+			t.Fatalf("Expected archive file %s: %v", name, err)
+		}
+	}
+
+	live, _ := logger.ReadAuditEvents(1, 0, 0)
+	if len(live) != 1 || live[0].ExtraMsg != "recent" {
+		t.Fatalf("Expected only the recent event to stay live, got %+v", live)
+	}
+
+	all, err := archiver.ReadAuditEvents(1, 0, 0, true)
+	if err != nil {
+		t.Fatalf("Failed to read with archive: %v", err)
+	}
+	if len(all) != 3 || all[0].ExtraMsg != "jan" || all[2].ExtraMsg != "recent" {
+		t.Fatalf("Expected jan, feb, recent, got %+v", all)
+	}
+	if _, ok := all[0].Metadata.(map[string]interface{}); !ok {
+		t.Fatalf("Expected archived metadata to round-trip, got %#v", all[0].Metadata)
+	}
+
+	// Time ranges select only the relevant months
+	febOnly, err := audit.ReadArchivedAuditEvents(archiver.Dir, 1, feb, feb)
+	if err != nil || len(febOnly) != 1 || febOnly[0].ExtraMsg != "feb" {
+		t.Fatalf("Expected only the feb event, got %+v (%v)", febOnly, err)
+	}
+
+	// A later run merges into the existing month file, and an event that was
+	// archived but not purged, as after a crash, is not archived twice
+	logger.CreateAuditEvent("carol", audit.ActionIndexDelete, "jan-late", jan+60, 1, nil)
+	logger.CreateAuditEvent("alice", audit.ActionIndexCreate, "jan", jan, 1, map[string]interface{}{"index": "logs"})
+	if _, err := archiver.ArchiveOnce(now); err != nil {
+		t.Fatalf("Second archive run failed: %v", err)
+	}
+	janEvents, err := audit.ReadArchivedAuditEvents(archiver.Dir, 1, jan, jan+3600)
+	if err != nil || len(janEvents) != 2 {
+		t.Fatalf("Expected 2 merged jan events, got %d (%v)", len(janEvents), err)
+	}
+
+	// Tampering is detected through the checksum
+	archivePath := filepath.Join(archiver.Dir, "org-2", "2025-01.jsonl.gz")
+	data, _ := os.ReadFile(archivePath)
+	data[len(data)-1] ^= 0xff
+	os.WriteFile(archivePath, data, 0644)
+	if _, err := audit.ReadArchivedAuditEvents(archiver.Dir, 2, 0, 0); !errors.Is(err, audit.ErrArchiveCorrupt) {
+		t.Fatalf("Expected ErrArchiveCorrupt, got %v", err)
+	}
+}
+
+func TestArchiverEncrypted(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := audit.NewKeyRing("k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("Failed to create key ring: %v", err)
+	}
+	logger, err := audit.NewEncryptedFileAuditLogger(filepath.Join(dir, "audit.log"), keys)
+	if err != nil {
+		t.Fatalf("Failed to create encrypted file audit logger: %v", err)
+	}
+
+	// A plaintext month archived before the logger was encrypted
+	now := time.Date(2025, time.June, 15, 0, 0, 0, 0, time.UTC)
+	jan := time.Date(2025, time.January, 10, 0, 0, 0, 0, time.UTC).Unix()
+	archiveDir := filepath.Join(dir, "archive")
+	legacyPath := filepath.Join(archiveDir, "org-1", "2025-01.jsonl.gz")
+	plainLogger, err := audit.NewFileAuditLogger(filepath.Join(dir, "plain.log"))
+	if err != nil {
+		t.Fatalf("Failed to create file audit logger: %v", err)
+	}
+	plainLogger.CreateAuditEvent("bob", audit.ActionIndexCreate, "legacy", jan-1, 1, nil)
+	plainArchiver := &audit.Archiver{Logger: plainLogger, Dir: archiveDir, OlderThan: 30 * 24 * time.Hour}
+	if _, err := plainArchiver.ArchiveOnce(now); err != nil {
+		t.Fatalf("Plaintext archive run failed: %v", err)
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		t.Fatalf("Expected plaintext archive file: %v", err)
+	}
+
+	logger.CreateAuditEvent("alice", audit.ActionIndexCreate, "secret", jan, 1, nil)
+
+	archiver := &audit.Archiver{Logger: logger, Dir: archiveDir, OlderThan: 30 * 24 * time.Hour}
+	if _, err := archiver.ArchiveOnce(now); err != nil {
+		t.Fatalf("Archive run failed: %v", err)
+	}
+
+	// The plaintext month is folded into the encrypted file and removed
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Fatalf("Expected %s to be removed, got %v", legacyPath, err)
+	}
+	data, err := os.ReadFile(legacyPath + ".enc")
+	if err != nil {
+		t.Fatalf("Expected encrypted archive file: %v", err)
+	}
+	if bytes.Contains(data, []byte("alice")) || bytes.Contains(data, []byte("secret")) {
+		t.Fatal("Expected the archive to be encrypted")
+	}
+
+	events, err := archiver.ReadAuditEvents(1, 0, 0, true)
+	if err != nil || len(events) != 2 || events[0].ExtraMsg != "legacy" || events[1].ExtraMsg != "secret" {
+		t.Fatalf("Expected legacy and secret events, got %+v (%v)", events, err)
+	}
+	if _, err := audit.ReadArchivedAuditEvents(archiveDir, 1, 0, 0); !errors.Is(err, audit.ErrRecordDecryption) {
+		t.Fatalf("Expected ErrRecordDecryption without keys, got %v", err)
+	}
+
+	// An archive file cannot be passed off as another org's
+	os.MkdirAll(filepath.Join(archiveDir, "org-2"), 0755)
+	os.WriteFile(filepath.Join(archiveDir, "org-2", "2025-01.jsonl.gz.enc"), data, 0644)
+	if _, err := audit.ReadEncryptedArchivedAuditEvents(archiveDir, keys, 2, 0, 0); !errors.Is(err, audit.ErrRecordDecryption) {
+		t.Fatalf("Expected ErrRecordDecryption for a moved archive, got %v", err)
+	}
+}
@@ -2,9 +2,12 @@
 package audit
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -47,11 +50,7 @@ func TestDBAuditLogger(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create DB audit logger: %v", err)
 	}
-	defer func() {
-		if dbLogger, ok := logger.(*DBAuditLogger); ok {
-			dbLogger.Close()
-		}
-	}()
+	defer logger.Close()
 	
 	// Test creating audit events
 	testCreateAuditEvents(t, logger)
@@ -60,6 +59,117 @@ func TestDBAuditLogger(t *testing.T) {
 	testReadAuditEvents(t, logger)
 }
 
+func TestDBAuditLoggerInvalidMetadata(t *testing.T) {
+	logger, err := NewDBAuditLogger(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DB audit logger: %v", err)
+	}
+	defer logger.Close()
+
+	// Invalid JSON already in storage must surface as an error, not be dropped
+	if _, err := logger.db.Exec(`INSERT INTO audit_events (username, action_string, extra_msg, epoch_timestamp_sec, org_id, metadata)
+		VALUES ('mallory', 'bad', '', 1, 999, '{not json')`); err != nil {
+		t.Fatalf("Failed to insert corrupt row: %v", err)
+	}
+	if _, err := logger.ReadAuditEvents(999, 0, 0); !errors.Is(err, ErrInvalidMetadata) {
+		t.Fatalf("Expected ErrInvalidMetadata, got %v", err)
+	}
+}
+
+func TestFileAuditLoggerInvalidLine(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := NewFileAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to create file audit logger: %v", err)
+	}
+	if err := logger.CreateAuditEvent("alice", ActionUserLogin, "", 1, 999, nil); err != nil {
+		t.Fatalf("Failed to create audit event: %v", err)
+	}
+
+	// A corrupt line must surface as an error, not be skipped
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	if _, err := file.WriteString(`{"username": "mallory", "metadata": {not json` + "\n"); err != nil {
+		t.Fatalf("Failed to write corrupt line: %v", err)
+	}
+	file.Close()
+	if _, err := logger.ReadAuditEvents(999, 0, 0); !errors.Is(err, ErrInvalidMetadata) {
+		t.Fatalf("Expected ErrInvalidMetadata, got %v", err)
+	}
+
+	// Purging keeps lines it cannot decode
+	if _, err := logger.PurgeAuditEvents(func(AuditEvent) bool { return true }); err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if data, _ := os.ReadFile(logPath); !strings.Contains(string(data), "mallory") {
+		t.Fatalf("Expected corrupt line to be kept, got %q", data)
+	}
+}
+
+func TestFileAuditLoggerTornLastLine(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := NewFileAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to create file audit logger: %v", err)
+	}
+	if err := logger.CreateAuditEvent("alice", ActionUserLogin, "", 1, 999, nil); err != nil {
+		t.Fatalf("Failed to create audit event: %v", err)
+	}
+
+	// A crash mid-write leaves an unterminated last line, which reads skip
+	file, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open audit log: %v", err)
+	}
+	if _, err := file.WriteString(`{"username": "bob", "actionStr`); err != nil {
+		t.Fatalf("Failed to write torn line: %v", err)
+	}
+	file.Close()
+	events, err := logger.ReadAuditEvents(999, 0, 0)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected the torn line to be skipped, got %v, %v", events, err)
+	}
+
+	// Purging keeps it unterminated
+	if _, err := logger.PurgeAuditEvents(func(AuditEvent) bool { return false }); err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if _, err := logger.ReadAuditEvents(999, 0, 0); err != nil {
+		t.Fatalf("Expected the torn line to be skipped after purging, got %v", err)
+	}
+
+	// Reopening the log removes it, so new records start on their own line
+	logger.Close()
+	logger, err = NewFileAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen file audit logger: %v", err)
+	}
+	defer logger.Close()
+	if err := logger.CreateAuditEvent("carol", ActionUserLogin, "", 2, 999, nil); err != nil {
+		t.Fatalf("Failed to create audit event: %v", err)
+	}
+	events, err = logger.ReadAuditEvents(999, 0, 0)
+	if err != nil || len(events) != 2 || events[1].Username != "carol" {
+		t.Fatalf("Expected alice and carol after repair, got %v, %v", events, err)
+	}
+}
+
+func TestDBAuditLoggerCloseRace(t *testing.T) {
+	logger, err := NewDBAuditLogger(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DB audit logger: %v", err)
+	}
+	logger.Close()
+
+	// A caller that passed the closed check before Close must still get ErrLoggerClosed
+	err = logger.queryEvents("SELECT "+eventColumns+" FROM audit_events", nil, true, func(int64, AuditEvent) error { return nil })
+	if !errors.Is(err, ErrLoggerClosed) {
+		t.Fatalf("Expected ErrLoggerClosed, got %v", err)
+	}
+}
+
 func TestFactoryPattern(t *testing.T) {
 	// Create temporary directories
 	tempDir, err := os.MkdirTemp("", "audit_factory_test")
@@ -113,6 +223,144 @@ func TestFactoryPattern(t *testing.T) {
 	dbLogger.Close()
 }
 
+func TestNamedLoggers(t *testing.T) {
+	tempDir := t.TempDir()
+
+	if err := InitNamedAuditLogger("security", FileLoggerType, map[string]string{
+		"filePath": filepath.Join(tempDir, "security.log"),
+	}); err != nil {
+		t.Fatalf("Failed to initialize security logger: %v", err)
+	}
+	defer UnregisterAuditLogger("security")
+
+	product := NewMemoryAuditLogger(0)
+	if err := RegisterAuditLogger("product", product); err != nil {
+		t.Fatalf("Failed to register product logger: %v", err)
+	}
+	defer UnregisterAuditLogger("product")
+
+	security, err := GetNamedAuditLogger("security")
+	if err != nil {
+		t.Fatalf("Failed to get security logger: %v", err)
+	}
+	if _, ok := security.(*FileAuditLogger); !ok {
+		t.Fatal("Expected FileAuditLogger for security stream")
+	}
+
+	logger, err := GetNamedAuditLogger("product")
+	if err != nil || logger != product {
+		t.Fatalf("Expected registered product logger, got %v (%v)", logger, err)
+	}
+
+	// Writes to one stream must not appear in the other
+	if err := security.CreateAuditEvent("alice", ActionUserPasswordReset, "", 100, 1, nil); err != nil {
+		t.Fatalf("Failed to create audit event: %v", err)
+	}
+	if product.Len() != 0 {
+		t.Fatalf("Expected product stream to be empty, got %d events", product.Len())
+	}
+
+	names := AuditLoggerNames()
+	found := 0
+	for _, name := range names {
+		if name == "security" || name == "product" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Fatalf("Expected security and product in %v", names)
+	}
+
+	if _, err := GetNamedAuditLogger("missing"); err == nil {
+		t.Fatal("Expected error for unknown logger name")
+	}
+	if err := RegisterAuditLogger("", product); err == nil {
+		t.Fatal("Expected error for empty logger name")
+	}
+}
+
+func TestReinitializationClosesPreviousLogger(t *testing.T) {
+	first := NewMemoryAuditLogger(0)
+	if err := RegisterAuditLogger("lifecycle", first); err != nil {
+		t.Fatalf("Failed to register logger: %v", err)
+	}
+
+	if err := InitNamedAuditLogger("lifecycle", MemoryLoggerType, nil); err != nil {
+		t.Fatalf("Failed to re-initialize logger: %v", err)
+	}
+	defer UnregisterAuditLogger("lifecycle")
+
+	if err := first.CreateAuditEvent("alice", ActionUserLogin, "", 100, 1, nil); !errors.Is(err, ErrLoggerClosed) {
+		t.Fatalf("Expected previous logger to be closed, got %v", err)
+	}
+}
+
+func TestShutdownAuditLogger(t *testing.T) {
+	defer func() {
+		// Leave a usable default logger for other tests
+		InitAuditLogger(MemoryLoggerType, nil)
+	}()
+
+	logger := NewMemoryAuditLogger(0)
+	if err := RegisterAuditLogger(DefaultLoggerName, logger); err != nil {
+		t.Fatalf("Failed to register logger: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ShutdownAuditLogger(ctx); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+
+	if err := logger.CreateAuditEvent("alice", ActionUserLogin, "", 100, 1, nil); !errors.Is(err, ErrLoggerClosed) {
+		t.Fatalf("Expected logger to be closed, got %v", err)
+	}
+	if _, err := GetAuditLogger(); err == nil {
+		t.Fatal("Expected no default logger after shutdown")
+	}
+
+	// A logger registered under two names waits for calls made through both
+	shared := NewMemoryAuditLogger(0)
+	RegisterAuditLogger(DefaultLoggerName, shared)
+	RegisterAuditLogger("alias", shared)
+	finish := make(chan struct{})
+	running := make(chan struct{})
+	go useLogger("alias", func(AuditLogger) error {
+		close(running)
+		<-finish
+		return nil
+	})
+	<-running
+	time.AfterFunc(20*time.Millisecond, func() { close(finish) })
+	if err := ShutdownAuditLogger(context.Background()); err != nil {
+		t.Fatalf("Failed to shut down: %v", err)
+	}
+	select {
+	case <-finish:
+	default:
+		t.Fatal("Expected shutdown to wait for the call made through the second name")
+	}
+
+	// A logger that never drains makes shutdown return at the deadline
+	stuck := NewMemoryAuditLogger(0)
+	RegisterAuditLogger(DefaultLoggerName, stuck)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go useLogger(DefaultLoggerName, func(AuditLogger) error {
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := ShutdownAuditLogger(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got %v", err)
+	}
+}
+
 func TestConvenienceFunctions(t *testing.T) {
 	// Create temporary directory
 	tempDir, err := os.MkdirTemp("", "audit_convenience_test")
@@ -232,4 +480,4 @@ func testReadAuditEvents(t *testing.T, logger AuditLogger) {
 	if dashID, ok := metadataMap["dashboardId"]; !ok || dashID != "dash-123" {
 		t.Fatalf("Metadata mismatch: %v", metadataMap)
 	}
-}
\ No newline at end of file
+}
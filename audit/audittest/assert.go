@@ -0,0 +1,80 @@
+// audit/audittest/assert.go
+package audittest
+
+import (
+	"strings"
+	"testing"
+
+	"AuditEventsModule/audit"
+)
+
+// EventSource is implemented by loggers that can list every stored event, such as audit.MemoryAuditLogger
+type EventSource interface {
+	Events() []audit.AuditEvent
+}
+
+// Match describes an expected audit event. Empty strings and a zero OrgID match anything;
+// use ExpectEventFunc for conditions Match cannot express.
+type Match struct {
+	Username         string
+	ActionString     string
+	OrgID            int64
+	ExtraMsgContains string
+}
+
+// Matches reports whether event satisfies every field set in m
+func (m Match) Matches(event audit.AuditEvent) bool {
+	return (m.Username == "" || event.Username == m.Username) &&
+		(m.ActionString == "" || event.ActionString == m.ActionString) &&
+		(m.OrgID == 0 || event.OrgID == m.OrgID) &&
+		(m.ExtraMsgContains == "" || strings.Contains(event.ExtraMsg, m.ExtraMsgContains))
+}
+
+// ExpectEvent fails the test unless source holds an event matching m, and returns the first match
+func ExpectEvent(t testing.TB, source EventSource, m Match) audit.AuditEvent {
+	t.Helper()
+	return ExpectEventFunc(t, source, m.Matches)
+}
+
+// ExpectAction fails the test unless username performed action, and returns the first match
+func ExpectAction(t testing.TB, source EventSource, action, username string) audit.AuditEvent {
+	t.Helper()
+	return ExpectEventFunc(t, source, Match{Username: username, ActionString: action}.Matches)
+}
+
+// ExpectEventFunc fails the test unless source holds an event for which match returns true
+func ExpectEventFunc(t testing.TB, source EventSource, match func(audit.AuditEvent) bool) audit.AuditEvent {
+	t.Helper()
+	events := source.Events()
+	for _, event := range events {
+		if match(event) {
+			return event
+		}
+	}
+	t.Fatalf("Expected matching audit event, got %d events: %+v", len(events), events)
+	return audit.AuditEvent{}
+}
+
+// ExpectNoEvent fails the test if source holds any event matching m
+func ExpectNoEvent(t testing.TB, source EventSource, m Match) {
+	t.Helper()
+	for _, event := range source.Events() {
+		if m.Matches(event) {
+			t.Fatalf("Unexpected audit event: %+v", event)
+		}
+	}
+}
+
+// ExpectEventCount fails the test unless exactly count events match m
+func ExpectEventCount(t testing.TB, source EventSource, m Match, count int) {
+	t.Helper()
+	matched := 0
+	for _, event := range source.Events() {
+		if m.Matches(event) {
+			matched++
+		}
+	}
+	if matched != count {
+		t.Fatalf("Expected %d matching audit events, got %d", count, matched)
+	}
+}
@@ -0,0 +1,360 @@
+// audit/audittest/conformance.go
+
+// Package audittest provides helpers for testing code that uses the audit package,
+// including a conformance suite for AuditLogger implementations.
+package audittest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"AuditEventsModule/audit"
+)
+
+// LoggerFactory returns a fresh, empty AuditLogger for a single conformance check.
+// Implementations should register any cleanup with t.Cleanup.
+type LoggerFactory func(t *testing.T) audit.AuditLogger
+
+// RunConformance runs the AuditLogger conformance suite against loggers built by newLogger
+func RunConformance(t *testing.T, newLogger LoggerFactory) {
+	t.Run("Ordering", func(t *testing.T) { testOrdering(t, newLogger(t)) })
+	t.Run("TimeRangeBoundaries", func(t *testing.T) { testTimeRangeBoundaries(t, newLogger(t)) })
+	t.Run("OrgIsolation", func(t *testing.T) { testOrgIsolation(t, newLogger(t)) })
+	t.Run("ZeroTimestamp", func(t *testing.T) { testZeroTimestamp(t, newLogger(t)) })
+	t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, newLogger(t)) })
+	t.Run("MetadataFidelity", func(t *testing.T) { testMetadataFidelity(t, newLogger(t)) })
+	t.Run("LargePayload", func(t *testing.T) { testLargePayload(t, newLogger(t)) })
+	t.Run("Errors", func(t *testing.T) { testErrors(t, newLogger(t)) })
+	t.Run("Scan", func(t *testing.T) { testScan(t, newLogger(t)) })
+	t.Run("Purge", func(t *testing.T) { testPurge(t, newLogger(t)) })
+	t.Run("Lifecycle", func(t *testing.T) { testLifecycle(t, newLogger(t)) })
+}
+
+func mustCreate(t *testing.T, logger audit.AuditLogger, username, action, extraMsg string, timestamp, orgID int64, metadata interface{}) {
+	t.Helper()
+	if err := logger.CreateAuditEvent(username, action, extraMsg, timestamp, orgID, metadata); err != nil {
+		t.Fatalf("Failed to create audit event: %v", err)
+	}
+}
+
+func mustRead(t *testing.T, logger audit.AuditLogger, orgID, startEpochSec, endEpochSec int64) []audit.AuditEvent {
+	t.Helper()
+	events, err := logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+	if err != nil {
+		t.Fatalf("Failed to read audit events: %v", err)
+	}
+	return events
+}
+
+// testOrdering checks events come back by ascending timestamp, ties in insertion order
+func testOrdering(t *testing.T, logger audit.AuditLogger) {
+	timestamps := []int64{300, 100, 200, 100, 300}
+	for i, ts := range timestamps {
+		mustCreate(t, logger, "alice", audit.ActionUserLogin, fmt.Sprintf("event-%d", i), ts, 1, nil)
+	}
+
+	events := mustRead(t, logger, 1, 0, 0)
+	expected := []string{"event-1", "event-3", "event-2", "event-0", "event-4"}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(events))
+	}
+	for i, event := range events {
+		if event.ExtraMsg != expected[i] {
+			t.Fatalf("Expected %s at position %d, got %s", expected[i], i, event.ExtraMsg)
+		}
+	}
+}
+
+// testTimeRangeBoundaries checks start and end are inclusive and an end of 0 is unbounded
+func testTimeRangeBoundaries(t *testing.T, logger audit.AuditLogger) {
+	for _, ts := range []int64{99, 100, 150, 200, 201} {
+		mustCreate(t, logger, "alice", audit.ActionUserLogin, "", ts, 1, nil)
+	}
+
+	testCases := []struct {
+		start, end int64
+		expected   int
+	}{
+		{100, 200, 3},
+		{100, 100, 1},
+		{200, 200, 1},
+		{101, 149, 0},
+		{150, 0, 3},
+		{0, 0, 5},
+		{202, 0, 0},
+	}
+	for _, tc := range testCases {
+		events := mustRead(t, logger, 1, tc.start, tc.end)
+		if len(events) != tc.expected {
+			t.Fatalf("Range [%d, %d]: expected %d events, got %d", tc.start, tc.end, tc.expected, len(events))
+		}
+		for _, event := range events {
+			if event.EpochTimestampSec < tc.start || (tc.end != 0 && event.EpochTimestampSec > tc.end) {
+				t.Fatalf("Range [%d, %d]: event at %d out of range", tc.start, tc.end, event.EpochTimestampSec)
+			}
+		}
+	}
+}
+
+// testOrgIsolation checks reads only return events for the requested organization
+func testOrgIsolation(t *testing.T, logger audit.AuditLogger) {
+	mustCreate(t, logger, "alice", audit.ActionUserLogin, "", 100, 1, nil)
+	mustCreate(t, logger, "bob", audit.ActionUserLogin, "", 100, 2, nil)
+	mustCreate(t, logger, "carol", audit.ActionUserLogin, "", 100, 2, nil)
+
+	if events := mustRead(t, logger, 1, 0, 0); len(events) != 1 || events[0].Username != "alice" {
+		t.Fatalf("Expected only alice for org 1, got %+v", events)
+	}
+	if events := mustRead(t, logger, 2, 0, 0); len(events) != 2 {
+		t.Fatalf("Expected 2 events for org 2, got %d", len(events))
+	}
+	if events := mustRead(t, logger, 3, 0, 0); len(events) != 0 {
+		t.Fatalf("Expected no events for org 3, got %d", len(events))
+	}
+}
+
+// testZeroTimestamp checks a zero timestamp is replaced with the current time
+func testZeroTimestamp(t *testing.T, logger audit.AuditLogger) {
+	before := time.Now().Unix()
+	mustCreate(t, logger, "alice", audit.ActionUserLogin, "", 0, 1, nil)
+	after := time.Now().Unix()
+
+	events := mustRead(t, logger, 1, 0, 0)
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if ts := events[0].EpochTimestampSec; ts < before || ts > after {
+		t.Fatalf("Expected timestamp in [%d, %d], got %d", before, after, ts)
+	}
+}
+
+// testConcurrency checks concurrent writers and readers do not lose or corrupt events
+func testConcurrency(t *testing.T, logger audit.AuditLogger) {
+	const writers = 8
+	const perWriter = 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*perWriter*2)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				metadata := map[string]interface{}{"writer": w, "seq": i}
+				if err := logger.CreateAuditEvent(fmt.Sprintf("user-%d", w), audit.ActionIndexUpdate, "", 100, 1, metadata); err != nil {
+					errs <- err
+				}
+				if _, err := logger.ReadAuditEvents(1, 0, 0); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Concurrent operation failed: %v", err)
+	}
+
+	events := mustRead(t, logger, 1, 0, 0)
+	if len(events) != writers*perWriter {
+		t.Fatalf("Expected %d events, got %d", writers*perWriter, len(events))
+	}
+	seen := make(map[string]bool)
+	for _, event := range events {
+		metadata, ok := event.Metadata.(map[string]interface{})
+		if !ok {
+			t.Fatalf("Unexpected metadata %#v", event.Metadata)
+		}
+		key := fmt.Sprintf("%v/%v", metadata["writer"], metadata["seq"])
+		if seen[key] {
+			t.Fatalf("Duplicate event %s", key)
+		}
+		seen[key] = true
+	}
+}
+
+// testMetadataFidelity checks the metadata round-trip contract documented in the audit package
+func testMetadataFidelity(t *testing.T, logger audit.AuditLogger) {
+	var nilMap map[string]interface{}
+	testCases := []struct {
+		name     string
+		metadata interface{}
+		expected string
+	}{
+		{"nil", nil, "null"},
+		{"typed nil", nilMap, "null"},
+		{"empty object", map[string]interface{}{}, "{}"},
+		{"empty array", []interface{}{}, "[]"},
+		{"large integer", map[string]interface{}{"id": int64(9007199254740993)}, `{"id":9007199254740993}`},
+		{"float", map[string]interface{}{"ratio": 0.25}, `{"ratio":0.25}`},
+		{"nested", map[string]interface{}{"a": []interface{}{"x", true, nil}}, `{"a":["x",true,null]}`},
+		{"string", "plain", `"plain"`},
+		{"struct", struct {
+			Name string `json:"name"`
+		}{"dash"}, `{"name":"dash"}`},
+	}
+
+	for i, tc := range testCases {
+		orgID := int64(1000 + i)
+		mustCreate(t, logger, "alice", audit.ActionUserLogin, tc.name, 100, orgID, tc.metadata)
+
+		events := mustRead(t, logger, orgID, 0, 0)
+		if len(events) != 1 {
+			t.Fatalf("%s: expected 1 event, got %d", tc.name, len(events))
+		}
+
+		if tc.expected == "null" {
+			if events[0].Metadata != nil {
+				t.Fatalf("%s: expected nil metadata, got %#v", tc.name, events[0].Metadata)
+			}
+			continue
+		}
+
+		if metadataMap, ok := events[0].Metadata.(map[string]interface{}); ok {
+			for key, value := range metadataMap {
+				if _, isFloat := value.(float64); isFloat {
+					t.Fatalf("%s: expected json.Number for %q, got float64", tc.name, key)
+				}
+			}
+		}
+
+		actual, err := json.Marshal(events[0].Metadata)
+		if err != nil {
+			t.Fatalf("%s: failed to marshal metadata: %v", tc.name, err)
+		}
+		if string(actual) != tc.expected {
+			t.Fatalf("%s: expected %s, got %s", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+// testLargePayload checks large messages and metadata are stored without truncation
+func testLargePayload(t *testing.T, logger audit.AuditLogger) {
+	extraMsg := strings.Repeat("x", 256*1024)
+	blob := strings.Repeat("y\n\"z", 64*1024)
+	mustCreate(t, logger, "alice", audit.ActionLookupFileCreate, extraMsg, 100, 1, map[string]interface{}{"blob": blob})
+	mustCreate(t, logger, "bob", audit.ActionLookupFileCreate, "small", 101, 1, nil)
+
+	events := mustRead(t, logger, 1, 0, 0)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].ExtraMsg != extraMsg {
+		t.Fatalf("Extra message truncated to %d bytes", len(events[0].ExtraMsg))
+	}
+	metadata, ok := events[0].Metadata.(map[string]interface{})
+	if !ok || metadata["blob"] != blob {
+		t.Fatal("Metadata blob not preserved")
+	}
+	if events[1].ExtraMsg != "small" {
+		t.Fatalf("Expected following event intact, got %q", events[1].ExtraMsg)
+	}
+}
+
+// testErrors checks invalid input is rejected without storing a partial event
+func testErrors(t *testing.T, logger audit.AuditLogger) {
+	err := logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 100, 1, map[string]interface{}{"ch": make(chan int)})
+	if err == nil {
+		t.Fatal("Expected error for metadata that cannot be marshalled")
+	}
+	if events := mustRead(t, logger, 1, 0, 0); len(events) != 0 {
+		t.Fatalf("Expected rejected event not to be stored, got %d events", len(events))
+	}
+}
+
+// testScan checks optional scan support visits every event across organizations
+func testScan(t *testing.T, logger audit.AuditLogger) {
+	scanner, ok := logger.(audit.AuditScanner)
+	if !ok {
+		t.Skip("logger does not implement audit.AuditScanner")
+	}
+
+	mustCreate(t, logger, "alice", audit.ActionUserLogin, "", 100, 1, nil)
+	mustCreate(t, logger, "bob", audit.ActionUserLogin, "", 200, 2, map[string]interface{}{"n": 1})
+
+	seen := make(map[string]bool)
+	err := scanner.ScanAuditEvents(func(event audit.AuditEvent) error {
+		seen[event.Username] = true
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	if len(seen) != 2 || !seen["alice"] || !seen["bob"] {
+		t.Fatalf("Expected to scan alice and bob, got %v", seen)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = scanner.ScanAuditEvents(func(audit.AuditEvent) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("Expected scan to stop on callback error, got %v after %d calls", err, calls)
+	}
+}
+
+// testPurge checks optional purge support removes exactly the matching events
+func testPurge(t *testing.T, logger audit.AuditLogger) {
+	purger, ok := logger.(audit.AuditPurger)
+	if !ok {
+		t.Skip("logger does not implement audit.AuditPurger")
+	}
+
+	for i, ts := range []int64{100, 200, 300} {
+		mustCreate(t, logger, "alice", audit.ActionUserLogin, fmt.Sprintf("org1-%d", i), ts, 1, map[string]interface{}{"seq": i})
+		mustCreate(t, logger, "bob", audit.ActionUserLogin, fmt.Sprintf("org2-%d", i), ts, 2, nil)
+	}
+
+	deleted, err := purger.PurgeAuditEvents(func(event audit.AuditEvent) bool {
+		return event.EpochTimestampSec < 250
+	})
+	if err != nil {
+		t.Fatalf("Failed to purge: %v", err)
+	}
+	if deleted[1] != 2 || deleted[2] != 2 {
+		t.Fatalf("Expected 2 deletions per org, got %v", deleted)
+	}
+
+	events := mustRead(t, logger, 1, 0, 0)
+	if len(events) != 1 || events[0].ExtraMsg != "org1-2" {
+		t.Fatalf("Expected only org1-2 to remain, got %+v", events)
+	}
+	if _, ok := events[0].Metadata.(map[string]interface{}); !ok {
+		t.Fatalf("Expected surviving metadata intact, got %#v", events[0].Metadata)
+	}
+
+	// Writes keep working after a purge
+	mustCreate(t, logger, "carol", audit.ActionUserLogin, "after", 400, 1, nil)
+	if events := mustRead(t, logger, 1, 0, 0); len(events) != 2 {
+		t.Fatalf("Expected 2 events after purge and write, got %d", len(events))
+	}
+}
+
+// testLifecycle checks Flush and Close semantics
+func testLifecycle(t *testing.T, logger audit.AuditLogger) {
+	mustCreate(t, logger, "alice", audit.ActionUserLogin, "", 100, 1, nil)
+
+	if err := logger.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Expected second Close to be a no-op, got %v", err)
+	}
+
+	err := logger.CreateAuditEvent("alice", audit.ActionUserLogout, "", 101, 1, nil)
+	if !errors.Is(err, audit.ErrLoggerClosed) {
+		t.Fatalf("Expected ErrLoggerClosed after Close, got %v", err)
+	}
+}
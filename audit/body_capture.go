@@ -0,0 +1,194 @@
+// audit/body_capture.go
+package audit
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// DefaultBodyCaptureLimit is the number of body bytes captured when BodyCapture sets no limit
+const DefaultBodyCaptureLimit = 64 * 1024
+
+// DefaultSensitiveBodyKeys are redacted from captured bodies when BodyCapture.RedactKeys is nil
+var DefaultSensitiveBodyKeys = []string{"password", "passwd", "secret", "token", "accessToken", "refreshToken", "apiKey", "clientSecret"}
+
+// BodyCapture configures recording of request and response bodies in middleware events.
+// JSON and form bodies are recorded as objects so redaction can target their fields;
+// text bodies are recorded as strings with "key=value" and "key: value" pairs under
+// sensitive keys masked, and other bodies only by size.
+type BodyCapture struct {
+	// MaxRequestBytes limits the captured request body; DefaultBodyCaptureLimit when 0
+	MaxRequestBytes int
+
+	// Response enables response capture, limited by MaxResponseBytes
+	Response         bool
+	MaxResponseBytes int
+
+	// RedactKeys are object keys, matched case-insensitively at any depth, whose values
+	// are replaced with RedactedValue; DefaultSensitiveBodyKeys when nil
+	RedactKeys []string
+
+	// textPattern matches "key=value" pairs under RedactKeys in text bodies
+	textPattern *regexp.Regexp
+}
+
+// WithBodyCapture records request bodies, and optionally response bodies, in middleware events.
+// Request bodies are restored so handlers can still read them.
+func WithBodyCapture(capture BodyCapture) MiddlewareOption {
+	capture.textPattern = sensitiveTextPattern(capture.redactKeys())
+	return func(c *middlewareConfig) {
+		c.bodyCapture = &capture
+	}
+}
+
+// redactKeys returns RedactKeys, or DefaultSensitiveBodyKeys when it is nil
+func (c *BodyCapture) redactKeys() []string {
+	if c.RedactKeys == nil {
+		return DefaultSensitiveBodyKeys
+	}
+	return c.RedactKeys
+}
+
+// limit returns n, or DefaultBodyCaptureLimit when n is not positive
+func (c *BodyCapture) limit(n int) int {
+	if n <= 0 {
+		return DefaultBodyCaptureLimit
+	}
+	return n
+}
+
+// capturedBody is a prefix of a request or response body
+type capturedBody struct {
+	data        []byte
+	truncated   bool
+	contentType string
+	err         error // the body could not be read in full
+}
+
+// captureRequestBody reads up to limit bytes of the request body and restores the body
+// so the whole of it can still be read by the handler
+func captureRequestBody(r *http.Request, limit int) *capturedBody {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, int64(limit)+1))
+	r.Body = readCloser{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+
+	body := &capturedBody{data: data, contentType: r.Header.Get("Content-Type"), err: err}
+	if len(data) > limit {
+		body.data, body.truncated = data[:limit], true
+	}
+	return body
+}
+
+// readCloser joins a reader with the closer of the body it replaces
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyRecorder keeps the first limit bytes written to a response
+type bodyRecorder struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// record appends as much of p as fits within the limit
+func (b *bodyRecorder) record(p []byte) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		p, b.truncated = p[:max(room, 0)], true
+	}
+	b.buf.Write(p)
+}
+
+// addMetadata records the body under key, decoded according to its content type
+func (b *capturedBody) addMetadata(metadata map[string]interface{}, key string, capture *BodyCapture) {
+	if b == nil || (len(b.data) == 0 && !b.truncated && b.err == nil) {
+		return
+	}
+	if b.truncated {
+		metadata[key+"Truncated"] = true
+	}
+	if b.err != nil {
+		metadata[key+"CaptureError"] = true
+	}
+	incomplete := b.truncated || b.err != nil
+
+	mediaType, _, _ := mime.ParseMediaType(b.contentType)
+	redactKeys := capture.redactKeys()
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		// An incomplete or invalid document cannot be redacted by key, so it is not recorded
+		var value interface{}
+		if incomplete || decodeJSON(b.data, &value) != nil {
+			return
+		}
+		metadata[key] = redactKeysIn(value, redactKeys)
+	case mediaType == "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(b.data))
+		if incomplete || err != nil {
+			return
+		}
+		form := make(map[string]interface{}, len(values))
+		for name, v := range values {
+			if len(v) == 1 {
+				form[name] = v[0]
+			} else {
+				form[name] = v
+			}
+		}
+		metadata[key] = redactKeysIn(form, redactKeys)
+	case strings.HasPrefix(mediaType, "text/"):
+		metadata[key] = redactKeysInText(string(b.data), capture.textPattern)
+	default:
+		metadata[key+"Size"] = len(b.data)
+	}
+}
+
+// sensitiveTextPattern matches "key=value", "key: value" and "key":"value" pairs under
+// keys in free text, or returns nil when there are no keys
+func sensitiveTextPattern(keys []string) *regexp.Regexp {
+	if len(keys) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		quoted[i] = regexp.QuoteMeta(key)
+	}
+	return regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)(["']?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s&,;"']+)`)
+}
+
+// redactKeysInText replaces the values matched by pattern with RedactedValue
+func redactKeysInText(text string, pattern *regexp.Regexp) string {
+	if pattern == nil {
+		return text
+	}
+	return pattern.ReplaceAllString(text, "${1}${2}"+RedactedValue)
+}
+
+// redactKeysIn replaces the values of sensitive keys anywhere within a decoded JSON value
+func redactKeysIn(value interface{}, keys []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if containsFold(keys, key) {
+				v[key] = RedactedValue
+			} else {
+				v[key] = redactKeysIn(item, keys)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactKeysIn(item, keys)
+		}
+	}
+	return value
+}
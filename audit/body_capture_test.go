@@ -0,0 +1,116 @@
+// audit/body_capture_test.go
+package audit_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestAuditMiddlewareBodyCapture(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	middleware := audit.AuditMiddleware(map[string]string{
+		"PUT /orgs/{id}/settings": audit.ActionOrgSettingsUpdate,
+	}, audit.WithLogger(logger), audit.WithBodyCapture(audit.BodyCapture{MaxRequestBytes: 64, Response: true, MaxResponseBytes: 8}))
+
+	var handlerBodies []string
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		handlerBodies = append(handlerBodies, string(body))
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "settings saved")
+	}))
+	serve := func(contentType, body string) map[string]interface{} {
+		logger.Reset()
+		req := httptest.NewRequest(http.MethodPut, "/orgs/1/settings", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return audittest.ExpectEvent(t, logger, audittest.Match{ActionString: audit.ActionOrgSettingsUpdate}).Metadata.(map[string]interface{})
+	}
+
+	// JSON is recorded as an object with sensitive keys redacted
+	jsonBody := `{"theme":"dark","smtp":{"password":"hunter2"}}`
+	metadata := serve("application/json", jsonBody)
+	requestBody := metadata["requestBody"].(map[string]interface{})
+	if requestBody["theme"] != "dark" || requestBody["smtp"].(map[string]interface{})["password"] != audit.RedactedValue {
+		t.Errorf("Unexpected JSON body capture: %v", requestBody)
+	}
+	if metadata["responseBody"] != "settings" || metadata["responseBodyTruncated"] != true {
+		t.Errorf("Unexpected response capture: %v, truncated %v", metadata["responseBody"], metadata["responseBodyTruncated"])
+	}
+
+	// Form data is recorded as an object
+	metadata = serve("application/x-www-form-urlencoded", "theme=light&token=abc")
+	form := metadata["requestBody"].(map[string]interface{})
+	if form["theme"] != "light" || form["token"] != audit.RedactedValue {
+		t.Errorf("Unexpected form body capture: %v", form)
+	}
+
+	// A truncated JSON body is flagged but not recorded, and the handler still sees all of it
+	large := `{"theme":"` + strings.Repeat("x", 100) + `"}`
+	metadata = serve("application/json", large)
+	if _, ok := metadata["requestBody"]; ok || metadata["requestBodyTruncated"] != true {
+		t.Errorf("Expected a truncated body to be flagged only, got %v", metadata)
+	}
+
+	// Text is recorded as a string with sensitive values masked
+	metadata = serve("text/plain", "user=bob password=hunter2 apiKey: 'k1'")
+	if metadata["requestBody"] != "user=bob password="+audit.RedactedValue+" apiKey: "+audit.RedactedValue {
+		t.Errorf("Unexpected text body capture: %v", metadata["requestBody"])
+	}
+
+	// Binary bodies are recorded by size
+	metadata = serve("application/octet-stream", "\x00\x01\x02")
+	if _, ok := metadata["requestBody"]; ok || metadata["requestBodySize"] == nil {
+		t.Errorf("Expected only the size of a binary body, got %v", metadata)
+	}
+
+	for i, want := range []string{jsonBody, "theme=light&token=abc", large, "user=bob password=hunter2 apiKey: 'k1'", "\x00\x01\x02"} {
+		if handlerBodies[i] != want {
+			t.Errorf("Handler %d read %q, expected %q", i, handlerBodies[i], want)
+		}
+	}
+}
+
+// failingReader returns its data and then an error
+type failingReader struct {
+	data string
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestAuditMiddlewareBodyCaptureError(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	handler := audit.AuditMiddleware(nil, audit.WithLogger(logger), audit.WithBodyCapture(audit.BodyCapture{}))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/dashboards", &failingReader{data: `{"title":"x"`})
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	metadata := audittest.ExpectEvent(t, logger, audittest.Match{}).Metadata.(map[string]interface{})
+	if metadata["requestBodyCaptureError"] != true {
+		t.Errorf("Expected the failed read to be flagged, got %v", metadata)
+	}
+	if _, ok := metadata["requestBody"]; ok {
+		t.Errorf("Expected a partially read body not to be recorded, got %v", metadata["requestBody"])
+	}
+}
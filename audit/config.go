@@ -0,0 +1,494 @@
+// audit/config.go
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables read by LoadConfigFromEnv
+const (
+	EnvLoggerType     = "AUDIT_TYPE"
+	EnvFilePath       = "AUDIT_FILE_PATH"
+	EnvFileKeyFile    = "AUDIT_FILE_KEY_FILE"
+	EnvDBPath         = "AUDIT_DB_PATH"
+	EnvDBKeyFile      = "AUDIT_DB_KEY_FILE"
+	EnvMemoryCapacity = "AUDIT_MEMORY_CAPACITY"
+)
+
+// FileLoggerConfig configures the file audit logger
+type FileLoggerConfig struct {
+	FilePath         string `json:"filePath" yaml:"filePath"`
+	EncryptionConfig `yaml:",inline"`
+}
+
+// DBLoggerConfig configures the database audit logger
+type DBLoggerConfig struct {
+	DBPath           string `json:"dbPath" yaml:"dbPath"`
+	EncryptionConfig `yaml:",inline"`
+}
+
+// EncryptionConfig enables encryption at rest for the file and DB loggers
+type EncryptionConfig struct {
+	// KeyFile holds the key ring (see LoadKeyRing)
+	KeyFile string `json:"keyFile,omitempty" yaml:"keyFile,omitempty"`
+
+	// Keys are base64-encoded keys given inline, by key ID.
+	// ActiveKeyID selects the key used for new data.
+	Keys        map[string]string `json:"keys,omitempty" yaml:"keys,omitempty"`
+	ActiveKeyID string            `json:"activeKeyId,omitempty" yaml:"activeKeyId,omitempty"`
+
+	// RequireEncryption rejects records stored in clear instead of trusting them.
+	// Enable it once ReencryptAuditEvents has encrypted the records from before encryption.
+	RequireEncryption bool `json:"requireEncryption,omitempty" yaml:"requireEncryption,omitempty"`
+}
+
+// setKeyFile switches the key source to the key ring file at path, keeping RequireEncryption
+func (c *EncryptionConfig) setKeyFile(path string) {
+	c.KeyFile, c.Keys, c.ActiveKeyID = path, nil, ""
+}
+
+// keyRing returns the configured key ring, or nil when encryption is not enabled
+func (c EncryptionConfig) keyRing() (*KeyRing, error) {
+	switch {
+	case c.KeyFile != "":
+		return LoadKeyRing(c.KeyFile)
+	case len(c.Keys) > 0:
+		return NewKeyRingFromBase64(c.ActiveKeyID, c.Keys)
+	}
+	return nil, nil
+}
+
+// validate reports problems with the encryption settings of section
+func (c EncryptionConfig) validate(section string, invalid func(field, format string, args ...interface{})) {
+	switch {
+	case c.KeyFile != "" && len(c.Keys) > 0:
+		invalid(section+".keys", "cannot be combined with %s.keyFile", section)
+	case len(c.Keys) > 0:
+		if _, err := NewKeyRingFromBase64(c.ActiveKeyID, c.Keys); err != nil {
+			invalid(section+".keys", "%v", err)
+		}
+	case c.ActiveKeyID != "":
+		invalid(section+".activeKeyId", "only used with %s.keys", section)
+	}
+	if c.RequireEncryption && c.KeyFile == "" && len(c.Keys) == 0 {
+		invalid(section+".requireEncryption", "requires %s.keyFile or %s.keys", section, section)
+	}
+}
+
+// encryptionKeyPrefix marks inline encryption keys in the map form of a configuration
+const encryptionKeyPrefix = "key."
+
+// toMap adds the encryption settings to the map form of a configuration
+func (c EncryptionConfig) toMap(config map[string]string) {
+	if c.KeyFile != "" {
+		config["keyFile"] = c.KeyFile
+	}
+	if c.ActiveKeyID != "" {
+		config["activeKeyId"] = c.ActiveKeyID
+	}
+	for id, key := range c.Keys {
+		config[encryptionKeyPrefix+id] = key
+	}
+	if c.RequireEncryption {
+		config["requireEncryption"] = "true"
+	}
+}
+
+// encryptionFromMap reads encryption settings from the map form of a configuration,
+// marking the keys it understands as allowed
+func encryptionFromMap(config map[string]string, allowed map[string]bool) (EncryptionConfig, error) {
+	allowed["keyFile"] = true
+	allowed["activeKeyId"] = true
+	allowed["requireEncryption"] = true
+	c := EncryptionConfig{
+		KeyFile:     config["keyFile"],
+		ActiveKeyID: config["activeKeyId"],
+	}
+	if value, ok := config["requireEncryption"]; ok {
+		required, err := strconv.ParseBool(value)
+		if err != nil {
+			return c, &ConfigError{Field: "requireEncryption", Message: fmt.Sprintf("must be true or false, got %q", value)}
+		}
+		c.RequireEncryption = required
+	}
+	for key, value := range config {
+		if id, ok := strings.CutPrefix(key, encryptionKeyPrefix); ok {
+			allowed[key] = true
+			if c.Keys == nil {
+				c.Keys = make(map[string]string)
+			}
+			c.Keys[id] = value
+		}
+	}
+	return c, nil
+}
+
+// MemoryLoggerConfig configures the in-memory audit logger
+type MemoryLoggerConfig struct {
+	// Capacity bounds the number of events kept; 0 means unbounded
+	Capacity int `json:"capacity" yaml:"capacity"`
+}
+
+// Config is the typed configuration for an audit logger. Only the section
+// matching Type may be set; backends added with RegisterBackend use Options.
+type Config struct {
+	Type    LoggerType          `json:"type" yaml:"type"`
+	File    *FileLoggerConfig   `json:"file,omitempty" yaml:"file,omitempty"`
+	DB      *DBLoggerConfig     `json:"db,omitempty" yaml:"db,omitempty"`
+	Memory  *MemoryLoggerConfig `json:"memory,omitempty" yaml:"memory,omitempty"`
+	Options map[string]string   `json:"options,omitempty" yaml:"options,omitempty"`
+
+	// Protected wraps the logger in a ProtectedAuditLogger, so the only logger
+	// created or registered from this configuration cannot be purged
+	Protected bool `json:"protected,omitempty" yaml:"protected,omitempty"`
+}
+
+// ConfigError describes a single invalid configuration field
+type ConfigError struct {
+	Field   string
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid audit config %s: %s", e.Field, e.Message)
+}
+
+// Validate checks the configuration and returns every problem found
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...interface{}) {
+		errs = append(errs, &ConfigError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	sections := map[LoggerType]bool{
+		FileLoggerType:   c.File != nil,
+		DBLoggerType:     c.DB != nil,
+		MemoryLoggerType: c.Memory != nil,
+	}
+
+	if c.Type == "" {
+		invalid("type", "logger type is required (one of %s)", registeredBackendList())
+	} else if b, ok := lookupBackend(c.Type); !ok {
+		invalid("type", "unsupported logger type %q (one of %s)", c.Type, registeredBackendList())
+	} else if b.validate != nil {
+		b.validate(c, invalid)
+	}
+
+	for _, loggerType := range []LoggerType{DBLoggerType, FileLoggerType, MemoryLoggerType} {
+		if sections[loggerType] && loggerType != c.Type {
+			invalid(string(loggerType), "section set but logger type is %q", c.Type)
+		}
+	}
+	if len(c.Options) > 0 && hasTypedConfig(c.Type) {
+		invalid("options", "only used by registered backends, configure the %s section instead", c.Type)
+	}
+
+	return errors.Join(errs...)
+}
+
+// registeredBackendList returns the registered logger types for error messages
+func registeredBackendList() string {
+	var names []string
+	for _, loggerType := range RegisteredBackends() {
+		names = append(names, string(loggerType))
+	}
+	return strings.Join(names, ", ")
+}
+
+// backendConfig converts cfg into the map form passed to backend constructors
+func (c Config) backendConfig() map[string]string {
+	config := make(map[string]string)
+	switch c.Type {
+	case FileLoggerType:
+		config["filePath"] = c.File.FilePath
+		c.File.toMap(config)
+	case DBLoggerType:
+		config["dbPath"] = c.DB.DBPath
+		c.DB.toMap(config)
+	case MemoryLoggerType:
+		if c.Memory != nil {
+			config["capacity"] = strconv.Itoa(c.Memory.Capacity)
+		}
+	default:
+		for key, value := range c.Options {
+			config[key] = value
+		}
+	}
+	return config
+}
+
+// LoadConfigFile reads and validates a configuration from a .json, .yaml or .yml file.
+// Unknown fields are rejected.
+func LoadConfigFile(path string) (Config, error) {
+	var cfg Config
+	if err := decodeConfigFile(path, &cfg); err != nil {
+		return cfg, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// decodeConfigFile strictly decodes a .json, .yaml or .yml file into v
+func decodeConfigFile(path string, v interface{}) error {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	return decodeConfigData(path, data, v)
+}
+
+// readConfigFile returns the contents of a configuration file
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit config file: %v", err)
+	}
+	return data, nil
+}
+
+// decodeConfigData strictly decodes data read from path, choosing the format by extension.
+// Anything after the first value is rejected, so a file caught mid-rewrite, with new
+// contents after the old, does not parse as the old configuration.
+func decodeConfigData(path string, data []byte, v interface{}) error {
+	var err, trailing error
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err = decoder.Decode(v); err == nil {
+			trailing = decoder.Decode(&json.RawMessage{})
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(v); err == nil {
+			trailing = decoder.Decode(&yaml.Node{})
+		}
+	default:
+		return fmt.Errorf("unsupported audit config file extension %q, expected .json, .yaml or .yml", ext)
+	}
+	if err == nil && trailing != io.EOF {
+		err = errors.New("unexpected data after the configuration")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse audit config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// LoadConfigFromEnv builds and validates a configuration from AUDIT_* environment variables
+func LoadConfigFromEnv() (Config, error) {
+	var cfg Config
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads the configuration file at path, if any, then applies AUDIT_* environment overrides
+func LoadConfig(path string) (Config, error) {
+	var data []byte
+	if path != "" {
+		var err error
+		if data, err = readConfigFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+	return loadConfigData(path, data)
+}
+
+// loadConfigData is LoadConfig for file contents that have already been read. The
+// result is validated once the environment overrides are applied, so they can supply
+// fields the file leaves out.
+func loadConfigData(path string, data []byte) (Config, error) {
+	var cfg Config
+	if path != "" {
+		if err := decodeConfigData(path, data, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// applyEnv overrides cfg with any AUDIT_* environment variables that are set
+func applyEnv(cfg *Config) error {
+	fileSet, dbSet, memorySet := cfg.File != nil, cfg.DB != nil, cfg.Memory != nil
+
+	if value, ok := os.LookupEnv(EnvLoggerType); ok {
+		cfg.Type = LoggerType(value)
+	}
+	if value, ok := os.LookupEnv(EnvFilePath); ok {
+		if cfg.File == nil {
+			cfg.File = &FileLoggerConfig{}
+		}
+		cfg.File.FilePath = value
+	}
+	if value, ok := os.LookupEnv(EnvFileKeyFile); ok {
+		if cfg.File == nil {
+			cfg.File = &FileLoggerConfig{}
+		}
+		cfg.File.setKeyFile(value)
+	}
+	if value, ok := os.LookupEnv(EnvDBPath); ok {
+		if cfg.DB == nil {
+			cfg.DB = &DBLoggerConfig{}
+		}
+		cfg.DB.DBPath = value
+	}
+	if value, ok := os.LookupEnv(EnvDBKeyFile); ok {
+		if cfg.DB == nil {
+			cfg.DB = &DBLoggerConfig{}
+		}
+		cfg.DB.setKeyFile(value)
+	}
+	if value, ok := os.LookupEnv(EnvMemoryCapacity); ok {
+		capacity, err := strconv.Atoi(value)
+		if err != nil {
+			return &ConfigError{Field: EnvMemoryCapacity, Message: fmt.Sprintf("must be an integer, got %q", value)}
+		}
+		cfg.Memory = &MemoryLoggerConfig{Capacity: capacity}
+	}
+
+	// Drop sections the environment added for other logger types so a shared
+	// environment does not fail validation; sections from the file are kept
+	if cfg.Type != FileLoggerType && !fileSet {
+		cfg.File = nil
+	}
+	if cfg.Type != DBLoggerType && !dbSet {
+		cfg.DB = nil
+	}
+	if cfg.Type != MemoryLoggerType && !memorySet {
+		cfg.Memory = nil
+	}
+	return nil
+}
+
+// ConfigFromMap converts the legacy map configuration into a validated Config,
+// rejecting keys a built-in logger type does not understand. For registered
+// backends the map is kept as Options and checked by the backend constructor.
+func ConfigFromMap(loggerType LoggerType, config map[string]string) (Config, error) {
+	cfg := Config{Type: loggerType}
+	if !hasTypedConfig(loggerType) {
+		if len(config) > 0 {
+			cfg.Options = make(map[string]string, len(config))
+			for key, value := range config {
+				cfg.Options[key] = value
+			}
+		}
+		return cfg, cfg.Validate()
+	}
+
+	allowed := map[string]bool{}
+
+	switch loggerType {
+	case FileLoggerType:
+		allowed["filePath"] = true
+		encryption, err := encryptionFromMap(config, allowed)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.File = &FileLoggerConfig{
+			FilePath:         config["filePath"],
+			EncryptionConfig: encryption,
+		}
+	case DBLoggerType:
+		allowed["dbPath"] = true
+		encryption, err := encryptionFromMap(config, allowed)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.DB = &DBLoggerConfig{
+			DBPath:           config["dbPath"],
+			EncryptionConfig: encryption,
+		}
+	case MemoryLoggerType:
+		allowed["capacity"] = true
+		cfg.Memory = &MemoryLoggerConfig{}
+		if value, ok := config["capacity"]; ok {
+			capacity, err := strconv.Atoi(value)
+			if err != nil {
+				return cfg, &ConfigError{Field: "capacity", Message: fmt.Sprintf("must be an integer, got %q", value)}
+			}
+			cfg.Memory.Capacity = capacity
+		}
+	}
+
+	var errs []error
+	for _, key := range sortedKeys(config) {
+		if !allowed[key] {
+			errs = append(errs, &ConfigError{Field: key, Message: fmt.Sprintf("unknown key for %s logger", loggerType)})
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return cfg, errors.Join(errs...)
+}
+
+// sortedKeys returns the keys of m in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// NewAuditLoggerFromConfig validates cfg and creates the configured audit logger without registering it
+func NewAuditLoggerFromConfig(cfg Config) (AuditLogger, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, ok := lookupBackend(cfg.Type)
+	if !ok {
+		return nil, fmt.Errorf("unsupported logger type: %s", cfg.Type)
+	}
+
+	logger, err := b.constructor(cfg.backendConfig())
+	if err != nil || !cfg.Protected {
+		return logger, err
+	}
+	return NewProtectedAuditLogger(logger), nil
+}
+
+// InitAuditLoggerFromConfig initializes the default audit logger from a typed configuration
+func InitAuditLoggerFromConfig(cfg Config) error {
+	return InitNamedAuditLoggerFromConfig(DefaultLoggerName, cfg)
+}
+
+// InitNamedAuditLoggerFromConfig initializes the audit logger registered under name from a typed configuration
+func InitNamedAuditLoggerFromConfig(name string, cfg Config) error {
+	logger, err := NewAuditLoggerFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	return RegisterAuditLogger(name, logger)
+}
@@ -0,0 +1,127 @@
+// audit/config_test.go
+package audit_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"AuditEventsModule/audit"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	yamlPath := filepath.Join(dir, "audit.yaml")
+	os.WriteFile(yamlPath, []byte("type: db\ndb:\n  dbPath: "+filepath.Join(dir, "audit.db")+"\n"), 0644)
+	cfg, err := audit.LoadConfigFile(yamlPath)
+	if err != nil {
+		t.Fatalf("Failed to load YAML config: %v", err)
+	}
+	if cfg.Type != audit.DBLoggerType || cfg.DB == nil || cfg.DB.DBPath == "" {
+		t.Fatalf("Unexpected YAML config: %+v", cfg)
+	}
+
+	jsonPath := filepath.Join(dir, "audit.json")
+	os.WriteFile(jsonPath, []byte(`{"type": "memory", "memory": {"capacity": 5}}`), 0644)
+	cfg, err = audit.LoadConfigFile(jsonPath)
+	if err != nil {
+		t.Fatalf("Failed to load JSON config: %v", err)
+	}
+	if cfg.Memory == nil || cfg.Memory.Capacity != 5 {
+		t.Fatalf("Unexpected JSON config: %+v", cfg)
+	}
+
+	// Unknown fields and mismatched sections are rejected
+	os.WriteFile(jsonPath, []byte(`{"type": "file", "file": {"path": "x.log"}}`), 0644)
+	if _, err := audit.LoadConfigFile(jsonPath); err == nil {
+		t.Fatal("Expected error for unknown field")
+	}
+	os.WriteFile(jsonPath, []byte(`{"type": "memory"}file": {"filePath": "a.log"}}`), 0644)
+	if _, err := audit.LoadConfigFile(jsonPath); err == nil {
+		t.Fatal("Expected error for data after the configuration")
+	}
+	os.WriteFile(yamlPath, []byte("type: memory\n---\ntype: file\n"), 0644)
+	if _, err := audit.LoadConfigFile(yamlPath); err == nil {
+		t.Fatal("Expected error for a second YAML document")
+	}
+	os.WriteFile(yamlPath, []byte("type: file\nfile:\n  filePath: a.log\ndb:\n  dbPath: a.db\n"), 0644)
+	if _, err := audit.LoadConfigFile(yamlPath); err == nil || !strings.Contains(err.Error(), "db") {
+		t.Fatalf("Expected error naming the extra db section, got %v", err)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv(audit.EnvLoggerType, "file")
+	t.Setenv(audit.EnvFilePath, "/var/log/audit.log")
+	t.Setenv(audit.EnvDBPath, "/var/lib/audit.db")
+
+	cfg, err := audit.LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to load config from environment: %v", err)
+	}
+	if cfg.File == nil || cfg.File.FilePath != "/var/log/audit.log" || cfg.DB != nil {
+		t.Fatalf("Unexpected config: %+v", cfg)
+	}
+
+	t.Setenv(audit.EnvLoggerType, "memory")
+	t.Setenv(audit.EnvMemoryCapacity, "lots")
+	if _, err := audit.LoadConfigFromEnv(); err == nil {
+		t.Fatal("Expected error for non-numeric capacity")
+	}
+}
+
+func TestLoadConfigEnvOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.json")
+
+	// The environment can supply fields the file leaves out
+	os.WriteFile(path, []byte(`{"type": "file"}`), 0644)
+	t.Setenv(audit.EnvFilePath, filepath.Join(dir, "audit.log"))
+	t.Setenv(audit.EnvDBPath, filepath.Join(dir, "audit.db"))
+	cfg, err := audit.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.File == nil || cfg.File.FilePath != filepath.Join(dir, "audit.log") || cfg.DB != nil {
+		t.Fatalf("Unexpected config: %+v", cfg)
+	}
+
+	// A key file from the environment replaces inline keys but keeps requireEncryption
+	os.WriteFile(path, []byte(`{"type": "file", "file": {"keys": {"k1": "`+base64.StdEncoding.EncodeToString(make([]byte, 32))+`"}, "activeKeyId": "k1", "requireEncryption": true}}`), 0644)
+	t.Setenv(audit.EnvFileKeyFile, filepath.Join(dir, "keys.json"))
+	cfg, err = audit.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.File.KeyFile != filepath.Join(dir, "keys.json") || cfg.File.Keys != nil || cfg.File.ActiveKeyID != "" || !cfg.File.RequireEncryption {
+		t.Fatalf("Unexpected encryption config: %+v", cfg.File.EncryptionConfig)
+	}
+
+	// Sections from the file are still checked against the logger type
+	os.WriteFile(path, []byte(`{"type": "file", "memory": {"capacity": 5}}`), 0644)
+	if _, err := audit.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "memory") {
+		t.Fatalf("Expected error naming the extra memory section, got %v", err)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	err := audit.Config{Type: audit.MemoryLoggerType, Memory: &audit.MemoryLoggerConfig{Capacity: -1}}.Validate()
+	var configErr *audit.ConfigError
+	if !errors.As(err, &configErr) || configErr.Field != "memory.capacity" {
+		t.Fatalf("Expected memory.capacity error, got %v", err)
+	}
+
+	if err := (audit.Config{Type: "kafka"}).Validate(); err == nil || !strings.Contains(err.Error(), "kafka") {
+		t.Fatalf("Expected unsupported type error, got %v", err)
+	}
+
+	// The legacy map form rejects unknown keys
+	_, err = audit.ConfigFromMap(audit.FileLoggerType, map[string]string{"filePath": "a.log", "dbPath": "a.db"})
+	if !errors.As(err, &configErr) || configErr.Field != "dbPath" {
+		t.Fatalf("Expected unknown key error for dbPath, got %v", err)
+	}
+}
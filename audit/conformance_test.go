@@ -0,0 +1,37 @@
+// audit/conformance_test.go
+package audit_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestFileAuditLoggerConformance(t *testing.T) {
+	audittest.RunConformance(t, func(t *testing.T) audit.AuditLogger {
+		logger, err := audit.NewFileAuditLogger(filepath.Join(t.TempDir(), "audit.log"))
+		if err != nil {
+			t.Fatalf("Failed to create file audit logger: %v", err)
+		}
+		return logger
+	})
+}
+
+func TestDBAuditLoggerConformance(t *testing.T) {
+	audittest.RunConformance(t, func(t *testing.T) audit.AuditLogger {
+		logger, err := audit.NewDBAuditLogger(filepath.Join(t.TempDir(), "audit.db"))
+		if err != nil {
+			t.Fatalf("Failed to create DB audit logger: %v", err)
+		}
+		t.Cleanup(func() { logger.Close() })
+		return logger
+	})
+}
+
+func TestMemoryAuditLoggerConformance(t *testing.T) {
+	audittest.RunConformance(t, func(t *testing.T) audit.AuditLogger {
+		return audit.NewMemoryAuditLogger(0)
+	})
+}
@@ -0,0 +1,51 @@
+// audit/context.go
+package audit
+
+import (
+	"context"
+)
+
+// AuditLoggerKey is the context key for an explicitly provided audit logger
+const AuditLoggerKey ContextKey = "audit_logger"
+
+// ContextWithAuditLogger returns a copy of ctx carrying logger, so code that only
+// receives a context can audit without touching the package-level loggers
+func ContextWithAuditLogger(ctx context.Context, logger AuditLogger) context.Context {
+	return context.WithValue(ctx, AuditLoggerKey, logger)
+}
+
+// AuditLoggerFromContext returns the audit logger carried by ctx, if any
+func AuditLoggerFromContext(ctx context.Context) (AuditLogger, bool) {
+	logger, ok := ctx.Value(AuditLoggerKey).(AuditLogger)
+	return logger, ok && logger != nil
+}
+
+// useContextLogger runs fn with the logger carried by ctx, falling back to the default logger
+func useContextLogger(ctx context.Context, fn func(AuditLogger) error) error {
+	if logger, ok := AuditLoggerFromContext(ctx); ok {
+		return fn(logger)
+	}
+	return useLogger(DefaultLoggerName, fn)
+}
+
+// CreateAuditEventContext creates an audit event with the logger carried by ctx, or the default logger
+func CreateAuditEventContext(ctx context.Context, username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
+	return useContextLogger(ctx, func(logger AuditLogger) error {
+		return logger.CreateAuditEvent(username, actionString, extraMsg, epochTimestampSec, orgID, metadata)
+	})
+}
+
+// ReadAuditEventsContext reads audit events with the logger carried by ctx, or the default logger
+func ReadAuditEventsContext(ctx context.Context, orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	var events []AuditEvent
+	err := useContextLogger(ctx, func(logger AuditLogger) error {
+		var err error
+		events, err = logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
@@ -3,8 +3,10 @@ package audit
 
 import (
 	"database/sql"
-	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
@@ -12,7 +14,14 @@ import (
 
 // DBAuditLogger implements AuditLogger interface using database storage
 type DBAuditLogger struct {
-	db *sql.DB
+	db     *sql.DB
+	path   string
+	keys   *KeyRing
+	closed atomic.Bool
+
+	// requireEncryption rejects rows stored in clear, which anyone able to
+	// write to the database could otherwise forge
+	requireEncryption atomic.Bool
 }
 
 // NewDBAuditLogger creates a new DBAuditLogger
@@ -42,41 +51,85 @@ func NewDBAuditLogger(dbPath string) (*DBAuditLogger, error) {
 		return nil, fmt.Errorf("failed to create audit events table: %v", err)
 	}
 
+	if err := migrateEncryptionColumns(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	return &DBAuditLogger{
-		db: db,
+		db:   db,
+		path: dbPath,
 	}, nil
 }
 
-// Close closes the database connection
+// Flush is a no-op because every insert is committed immediately
+func (l *DBAuditLogger) Flush() error {
+	if l.closed.Load() {
+		return ErrLoggerClosed
+	}
+	return nil
+}
+
+// Close closes the database connection once in-flight queries have finished
 func (l *DBAuditLogger) Close() error {
+	if !l.closed.CompareAndSwap(false, true) {
+		return nil
+	}
 	return l.db.Close()
 }
 
+// closedErr returns ErrLoggerClosed in place of err when the logger was closed while
+// the failed operation was starting, so racing callers see the same error as later ones
+func (l *DBAuditLogger) closedErr(err error) error {
+	if l.closed.Load() {
+		return ErrLoggerClosed
+	}
+	return err
+}
+
 // CreateAuditEvent logs a user action to the database
 func (l *DBAuditLogger) CreateAuditEvent(username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
+	if l.closed.Load() {
+		return ErrLoggerClosed
+	}
+
 	// If timestamp is 0, use current time
 	if epochTimestampSec == 0 {
 		epochTimestampSec = time.Now().Unix()
 	}
 
-	var metadataJSON []byte
-	var err error
+	metadataJSON, err := encodeMetadata(metadata)
+	if err != nil {
+		return err
+	}
 
-	if metadata != nil {
-		metadataJSON, err = json.Marshal(metadata)
+	// Store nil metadata as NULL so it reads back as nil
+	var metadataValue interface{}
+	if metadataJSON != nil {
+		metadataValue = string(metadataJSON)
+	}
+
+	// Encrypt the sensitive columns under a fresh data key when a key ring is configured
+	var extraMsgValue interface{} = extraMsg
+	var keyID, dataKeyValue interface{}
+	if l.keys != nil {
+		row := AuditEvent{Username: username, ActionString: actionString, EpochTimestampSec: epochTimestampSec, OrgID: orgID}
+		envelope, err := l.sealRow(row, extraMsg, metadataJSON)
 		if err != nil {
-			return fmt.Errorf("failed to marshal metadata: %v", err)
+			return err
 		}
+		extraMsgValue, metadataValue = envelope.extraMsg, envelope.metadata
+		keyID, dataKeyValue = envelope.keyID, envelope.wrappedKey
 	}
 
 	insertSQL := `
-	INSERT INTO audit_events (username, action_string, extra_msg, epoch_timestamp_sec, org_id, metadata)
-	VALUES (?, ?, ?, ?, ?, ?)
+	INSERT INTO audit_events (username, action_string, extra_msg, epoch_timestamp_sec, org_id, metadata, key_id, data_key)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = l.db.Exec(insertSQL, username, actionString, extraMsg, epochTimestampSec, orgID, string(metadataJSON))
+	_, err = l.db.Exec(insertSQL, username, actionString, extraMsgValue, epochTimestampSec, orgID, metadataValue, keyID, dataKeyValue)
 	if err != nil {
-		return fmt.Errorf("failed to insert audit event: %v", err)
+		return l.closedErr(fmt.Errorf("failed to insert audit event: %v", err))
 	}
 
 	return nil
@@ -84,8 +137,12 @@ func (l *DBAuditLogger) CreateAuditEvent(username, actionString, extraMsg string
 
 // ReadAuditEvents reads audit events from the database for a specific organization and time range
 func (l *DBAuditLogger) ReadAuditEvents(orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	if l.closed.Load() {
+		return nil, ErrLoggerClosed
+	}
+
 	query := `
-	SELECT username, action_string, extra_msg, epoch_timestamp_sec, org_id, metadata
+	SELECT ` + eventColumns + `
 	FROM audit_events
 	WHERE org_id = ? AND epoch_timestamp_sec >= ?
 	`
@@ -96,44 +153,355 @@ func (l *DBAuditLogger) ReadAuditEvents(orgID int64, startEpochSec, endEpochSec
 		args = append(args, endEpochSec)
 	}
 
-	query += " ORDER BY epoch_timestamp_sec ASC"
+	query += " ORDER BY epoch_timestamp_sec ASC, id ASC"
+
+	var events []AuditEvent
+	err := l.queryEvents(query, args, true, func(id int64, event AuditEvent) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// ScanAuditEvents calls fn for every stored event in timestamp order
+func (l *DBAuditLogger) ScanAuditEvents(fn func(AuditEvent) error) error {
+	if l.closed.Load() {
+		return ErrLoggerClosed
+	}
+
+	query := "SELECT " + eventColumns + " FROM audit_events ORDER BY epoch_timestamp_sec ASC, id ASC"
+	return l.queryEvents(query, nil, true, func(id int64, event AuditEvent) error {
+		return fn(event)
+	})
+}
+
+// PurgeAuditEvents deletes the events matching shouldPurge from the database, keeping
+// events covered by the legal holds stored next to it
+func (l *DBAuditLogger) PurgeAuditEvents(shouldPurge func(AuditEvent) bool) (map[int64]int, error) {
+	if l.closed.Load() {
+		return nil, ErrLoggerClosed
+	}
+	holds, err := l.LegalHolds()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int64
+	deleted := make(map[int64]int)
+
+	// Rows with undecodable metadata are still subject to purging
+	query := "SELECT " + eventColumns + " FROM audit_events"
+	err = l.queryEvents(query, nil, false, func(id int64, event AuditEvent) error {
+		if !holdsCover(holds, event) && shouldPurge(event) {
+			ids = append(ids, id)
+			deleted[event.OrgID]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := l.deleteRows(ids); err != nil {
+		return nil, err
+	}
+
+	return deleted, nil
+}
+
+// PurgeExpiredAuditEvents deletes the events past their retention period at now in a
+// single statement, keeping those covered by holds or by the legal holds stored next
+// to the database
+func (l *DBAuditLogger) PurgeExpiredAuditEvents(policy RetentionPolicy, now time.Time, holds []LegalHold) (map[int64]int, error) {
+	if l.closed.Load() {
+		return nil, ErrLoggerClosed
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	stored, err := l.LegalHolds()
+	if err != nil {
+		return nil, err
+	}
+	holds = append(stored, holds...)
+
+	where, args := retentionWhere(policy, now)
+	if where == "" {
+		return map[int64]int{}, nil
+	}
+	if held, heldArgs := holdsWhere(holds); held != "" {
+		where = "(" + where + ") AND NOT (" + held + ")"
+		args = append(args, heldArgs...)
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return nil, l.closedErr(fmt.Errorf("failed to begin transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT org_id, COUNT(*) FROM audit_events WHERE "+where+" GROUP BY org_id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count expired audit events: %v", err)
+	}
+	deleted := make(map[int64]int)
+	for rows.Next() {
+		var orgID int64
+		var count int
+		if err := rows.Scan(&orgID, &count); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to count expired audit events: %v", err)
+		}
+		deleted[orgID] = count
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count expired audit events: %v", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM audit_events WHERE "+where, args...); err != nil {
+		return nil, fmt.Errorf("failed to delete expired audit events: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit audit event deletion: %v", err)
+	}
+	return deleted, nil
+}
+
+// LegalHolds returns the active holds stored at LegalHoldPath of the database path
+func (l *DBAuditLogger) LegalHolds() ([]LegalHold, error) {
+	return readLegalHolds(LegalHoldPath(l.path))
+}
+
+// KeyRing returns the key ring rows are encrypted with, or nil for a plaintext database
+func (l *DBAuditLogger) KeyRing() *KeyRing {
+	return l.keys
+}
+
+// retentionWhere returns an SQL condition matching the events policy expires at now,
+// applying overrides in the same order as RetentionPolicy.RetentionFor
+func retentionWhere(policy RetentionPolicy, now time.Time) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	cutoff := func(retention time.Duration) int64 {
+		return now.Add(-retention).Unix()
+	}
+
+	// Per-org per-action overrides
+	orgActions := make(map[string][]int64)
+	for _, orgID := range sortedOrgIDs(policy.OrgActions) {
+		for _, action := range sortedActions(policy.OrgActions[orgID]) {
+			orgActions[action] = append(orgActions[action], orgID)
+			if retention := policy.OrgActions[orgID][action]; retention > 0 {
+				clauses = append(clauses, "(org_id = ? AND action_string = ? AND epoch_timestamp_sec < ?)")
+				args = append(args, orgID, action, cutoff(retention))
+			}
+		}
+	}
+
+	// Per-action overrides, except in orgs overriding the same action
+	for _, action := range sortedActions(policy.Actions) {
+		if retention := policy.Actions[action]; retention > 0 {
+			clause := "action_string = ? AND epoch_timestamp_sec < ?"
+			args = append(args, action, cutoff(retention))
+			if orgIDs := orgActions[action]; len(orgIDs) > 0 {
+				clause += " AND org_id NOT IN (" + placeholders(len(orgIDs)) + ")"
+				args = appendAll(args, orgIDs)
+			}
+			clauses = append(clauses, "("+clause+")")
+		}
+	}
+
+	// Per-org overrides, except for actions with their own override
+	for _, orgID := range sortedOrgIDs(policy.Orgs) {
+		retention := policy.Orgs[orgID]
+		if retention <= 0 {
+			continue
+		}
+		clause := "org_id = ? AND epoch_timestamp_sec < ?"
+		args = append(args, orgID, cutoff(retention))
+		excluded := append(sortedActions(policy.Actions), sortedActions(policy.OrgActions[orgID])...)
+		if len(excluded) > 0 {
+			clause += " AND action_string NOT IN (" + placeholders(len(excluded)) + ")"
+			args = appendAll(args, excluded)
+		}
+		clauses = append(clauses, "("+clause+")")
+	}
+
+	// The default, for everything without an override
+	if policy.Default > 0 {
+		clause := "epoch_timestamp_sec < ?"
+		args = append(args, cutoff(policy.Default))
+		if orgIDs := sortedOrgIDs(policy.Orgs); len(orgIDs) > 0 {
+			clause += " AND org_id NOT IN (" + placeholders(len(orgIDs)) + ")"
+			args = appendAll(args, orgIDs)
+		}
+		if actions := sortedActions(policy.Actions); len(actions) > 0 {
+			clause += " AND action_string NOT IN (" + placeholders(len(actions)) + ")"
+			args = appendAll(args, actions)
+		}
+		for _, orgID := range sortedOrgIDs(policy.OrgActions) {
+			if actions := sortedActions(policy.OrgActions[orgID]); len(actions) > 0 {
+				clause += " AND NOT (org_id = ? AND action_string IN (" + placeholders(len(actions)) + "))"
+				args = append(args, orgID)
+				args = appendAll(args, actions)
+			}
+		}
+		clauses = append(clauses, "("+clause+")")
+	}
+
+	return strings.Join(clauses, " OR "), args
+}
+
+// holdsWhere returns an SQL condition matching the events covered by holds
+func holdsWhere(holds []LegalHold) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	for _, hold := range holds {
+		switch {
+		case hold.OrgID != 0 && hold.Username != "":
+			clauses = append(clauses, "(org_id = ? AND username = ?)")
+			args = append(args, hold.OrgID, hold.Username)
+		case hold.OrgID != 0:
+			clauses = append(clauses, "org_id = ?")
+			args = append(args, hold.OrgID)
+		case hold.Username != "":
+			clauses = append(clauses, "username = ?")
+			args = append(args, hold.Username)
+		default:
+			// A hold naming neither covers everything
+			return "1 = 1", nil
+		}
+	}
+	return strings.Join(clauses, " OR "), args
+}
+
+// placeholders returns n comma-separated SQL parameter placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// appendAll appends values to args
+func appendAll[T any](args []interface{}, values []T) []interface{} {
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return args
+}
+
+// sortedOrgIDs returns the keys of m in ascending order
+func sortedOrgIDs[V any](m map[int64]V) []int64 {
+	ids := make([]int64, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
 
+// sortedActions returns the keys of m in sorted order
+func sortedActions(m map[string]time.Duration) []string {
+	actions := make([]string, 0, len(m))
+	for action := range m {
+		actions = append(actions, action)
+	}
+	slices.Sort(actions)
+	return actions
+}
+
+// eventColumns lists the audit_events columns read by queryEvents, in scan order
+const eventColumns = "id, username, action_string, extra_msg, epoch_timestamp_sec, org_id, metadata, key_id, data_key"
+
+// queryEvents runs a query selecting eventColumns and calls fn for each row. When
+// strict is false, rows whose metadata cannot be decoded or whose encrypted columns
+// cannot be decrypted are passed on with an empty extra message and nil metadata.
+func (l *DBAuditLogger) queryEvents(query string, args []interface{}, strict bool, fn func(id int64, event AuditEvent) error) error {
 	rows, err := l.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query audit events: %v", err)
+		return l.closedErr(fmt.Errorf("failed to query audit events: %v", err))
 	}
 	defer rows.Close()
 
-	var events []AuditEvent
 	for rows.Next() {
+		var id int64
 		var event AuditEvent
-		var metadataStr sql.NullString
+		var metadataStr, keyID sql.NullString
+		var wrappedKey []byte
 
 		err := rows.Scan(
+			&id,
 			&event.Username,
 			&event.ActionString,
 			&event.ExtraMsg,
 			&event.EpochTimestampSec,
 			&event.OrgID,
 			&metadataStr,
+			&keyID,
+			&wrappedKey,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan audit event row: %v", err)
+			return fmt.Errorf("failed to scan audit event row: %v", err)
+		}
+
+		switch {
+		case keyID.Valid:
+			err = l.openRow(&event, &metadataStr, keyID.String, wrappedKey)
+		case l.requireEncryption.Load():
+			event.ExtraMsg, metadataStr = "", sql.NullString{}
+			err = fmt.Errorf("%w: row is stored in clear", ErrRecordDecryption)
+		}
+		if err != nil && strict {
+			return fmt.Errorf("audit event %d: %w", id, err)
 		}
 
-		if metadataStr.Valid && metadataStr.String != "" {
-			var metadata interface{}
-			if err := json.Unmarshal([]byte(metadataStr.String), &metadata); err == nil {
-				event.Metadata = metadata
+		if metadataStr.Valid {
+			event.Metadata, err = decodeMetadata([]byte(metadataStr.String))
+			if err != nil && strict {
+				return err
 			}
 		}
 
-		events = append(events, event)
+		if err := fn(id, event); err != nil {
+			return err
+		}
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating audit event rows: %v", err)
+		return l.closedErr(fmt.Errorf("error iterating audit event rows: %v", err))
 	}
 
-	return events, nil
-}
\ No newline at end of file
+	return nil
+}
+
+// deleteBatchSize bounds the number of ids deleted by one statement
+const deleteBatchSize = 500
+
+// deleteRows deletes the rows with the given ids in a single transaction
+func (l *DBAuditLogger) deleteRows(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return l.closedErr(fmt.Errorf("failed to begin transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		batch := ids[start:min(start+deleteBatchSize, len(ids))]
+		query := "DELETE FROM audit_events WHERE id IN (" + placeholders(len(batch)) + ")"
+		if _, err := tx.Exec(query, appendAll(nil, batch)...); err != nil {
+			return fmt.Errorf("failed to delete audit events: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit audit event deletion: %v", err)
+	}
+	return nil
+}
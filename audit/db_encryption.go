@@ -0,0 +1,254 @@
+// audit/db_encryption.go
+package audit
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// The extra_msg and metadata columns of an encrypted row are sealed with a random
+// per-row data key. The data key is stored in data_key, wrapped with the key ring key
+// named by key_id. Username, action, org and timestamp stay in clear so they can
+// still be indexed and queried.
+
+// NewEncryptedDBAuditLogger creates a DBAuditLogger that encrypts the extra_msg and
+// metadata columns of new rows. Rows written without encryption remain readable until
+// RequireEncryption is called or ReencryptAuditEvents has encrypted them.
+func NewEncryptedDBAuditLogger(dbPath string, keys *KeyRing) (*DBAuditLogger, error) {
+	if keys == nil {
+		return nil, fmt.Errorf("encrypted audit database requires a key ring")
+	}
+
+	logger, err := NewDBAuditLogger(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	logger.keys = keys
+	return logger, nil
+}
+
+// migrateEncryptionColumns adds the key_id and data_key columns to tables created
+// before column encryption existed
+func migrateEncryptionColumns(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(audit_events)")
+	if err != nil {
+		return fmt.Errorf("failed to inspect audit events table: %v", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, primaryKey int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &primaryKey); err != nil {
+			return fmt.Errorf("failed to inspect audit events table: %v", err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect audit events table: %v", err)
+	}
+	rows.Close()
+
+	for _, column := range []struct{ name, definition string }{
+		{"key_id", "key_id TEXT"},
+		{"data_key", "data_key BLOB"},
+	} {
+		if existing[column.name] {
+			continue
+		}
+		if _, err := db.Exec("ALTER TABLE audit_events ADD COLUMN " + column.definition); err != nil {
+			return fmt.Errorf("failed to add %s column to audit events table: %v", column.name, err)
+		}
+	}
+	return nil
+}
+
+// rowEnvelope holds the encrypted column values of a row and its wrapped data key
+type rowEnvelope struct {
+	extraMsg   string
+	metadata   interface{}
+	keyID      string
+	wrappedKey []byte
+}
+
+// sealRow encrypts the extra message and metadata of row under a new data key
+func (l *DBAuditLogger) sealRow(row AuditEvent, extraMsg string, metadataJSON []byte) (rowEnvelope, error) {
+	dataKey, err := newKey()
+	if err != nil {
+		return rowEnvelope{}, err
+	}
+	wrapped, err := l.keys.encrypt(purposeDataKey, dataKey)
+	if err != nil {
+		return rowEnvelope{}, fmt.Errorf("failed to wrap data key: %v", err)
+	}
+
+	envelope := rowEnvelope{keyID: wrapped.KeyID, wrappedKey: wrapped.Ciphertext}
+	if envelope.extraMsg, err = sealColumn(dataKey, "extra_msg", row, []byte(extraMsg)); err != nil {
+		return rowEnvelope{}, err
+	}
+	if metadataJSON != nil {
+		if envelope.metadata, err = sealColumn(dataKey, "metadata", row, metadataJSON); err != nil {
+			return rowEnvelope{}, err
+		}
+	}
+	return envelope, nil
+}
+
+// openRow decrypts the extra message and metadata of a scanned row in place
+func (l *DBAuditLogger) openRow(event *AuditEvent, metadata *sql.NullString, keyID string, wrappedKey []byte) error {
+	encryptedExtraMsg, encryptedMetadata := event.ExtraMsg, *metadata
+	event.ExtraMsg, *metadata = "", sql.NullString{}
+
+	if l.keys == nil {
+		return fmt.Errorf("%w: audit database is encrypted but no key ring is configured", ErrRecordDecryption)
+	}
+	dataKey, err := l.keys.decrypt(purposeDataKey, encryptedRecord{KeyID: keyID, Ciphertext: wrappedKey})
+	if err != nil {
+		return err
+	}
+
+	extraMsg, err := openColumn(dataKey, "extra_msg", *event, encryptedExtraMsg)
+	if err != nil {
+		return err
+	}
+	if encryptedMetadata.Valid {
+		plaintext, err := openColumn(dataKey, "metadata", *event, encryptedMetadata.String)
+		if err != nil {
+			return err
+		}
+		*metadata = sql.NullString{String: string(plaintext), Valid: true}
+	}
+	event.ExtraMsg = string(extraMsg)
+	return nil
+}
+
+// columnAdditionalData binds an encrypted column to the clear columns of its row, so
+// ciphertext cannot be moved to another row or column unnoticed
+func columnAdditionalData(column string, row AuditEvent) []byte {
+	data, _ := json.Marshal([]interface{}{column, row.Username, row.ActionString, row.EpochTimestampSec, row.OrgID})
+	return data
+}
+
+// sealColumn encrypts a column value with dataKey and encodes it as base64 text
+func sealColumn(dataKey []byte, column string, row AuditEvent, plaintext []byte) (string, error) {
+	ciphertext, err := seal(dataKey, plaintext, columnAdditionalData(column, row))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt %s: %v", column, err)
+	}
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// openColumn decrypts a column value produced by sealColumn
+func openColumn(dataKey []byte, column string, row AuditEvent, value string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not valid base64: %v", ErrRecordDecryption, column, err)
+	}
+	plaintext, err := open(dataKey, ciphertext, columnAdditionalData(column, row))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrRecordDecryption, column, err)
+	}
+	return plaintext, nil
+}
+
+// RequireEncryption makes reads fail with ErrRecordDecryption on rows stored in clear
+// instead of trusting them. It cannot be undone.
+func (l *DBAuditLogger) RequireEncryption() {
+	l.requireEncryption.Store(true)
+}
+
+// ReencryptAuditEvents brings every row under the active key of the key ring and returns
+// how many rows were updated. Encrypted rows only have their data key re-wrapped; rows
+// stored in clear are encrypted. Run it after rotating keys so retired keys can be removed.
+// Once it succeeds every row is encrypted, so the logger then requires encryption.
+func (l *DBAuditLogger) ReencryptAuditEvents() (int, error) {
+	if l.closed.Load() {
+		return 0, ErrLoggerClosed
+	}
+	if l.keys == nil {
+		return 0, fmt.Errorf("audit database has no key ring to encrypt with")
+	}
+
+	type update struct {
+		id int64
+		rowEnvelope
+	}
+	var updates []update
+
+	// Re-wrap the data keys of rows encrypted under other keys
+	rows, err := l.db.Query("SELECT id, key_id, data_key FROM audit_events WHERE key_id IS NOT NULL AND key_id != ?", l.keys.ActiveKeyID())
+	if err != nil {
+		return 0, l.closedErr(fmt.Errorf("failed to query audit events: %v", err))
+	}
+	for rows.Next() {
+		var id int64
+		var wrapped encryptedRecord
+		if err := rows.Scan(&id, &wrapped.KeyID, &wrapped.Ciphertext); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan audit event row: %v", err)
+		}
+		dataKey, err := l.keys.decrypt(purposeDataKey, wrapped)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("audit event %d: %w", id, err)
+		}
+		rewrapped, err := l.keys.encrypt(purposeDataKey, dataKey)
+		if err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to wrap data key: %v", err)
+		}
+		updates = append(updates, update{id: id, rowEnvelope: rowEnvelope{keyID: rewrapped.KeyID, wrappedKey: rewrapped.Ciphertext}})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating audit event rows: %v", err)
+	}
+
+	// Encrypt rows stored in clear
+	var sealed []update
+	query := "SELECT " + eventColumns + " FROM audit_events WHERE key_id IS NULL"
+	err = l.queryEvents(query, nil, true, func(id int64, event AuditEvent) error {
+		metadataJSON, err := encodeMetadata(event.Metadata)
+		if err != nil {
+			return err
+		}
+		envelope, err := l.sealRow(event, event.ExtraMsg, metadataJSON)
+		if err != nil {
+			return err
+		}
+		sealed = append(sealed, update{id: id, rowEnvelope: envelope})
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return 0, l.closedErr(fmt.Errorf("failed to begin transaction: %v", err))
+	}
+	defer tx.Rollback()
+
+	for _, u := range updates {
+		if _, err := tx.Exec("UPDATE audit_events SET key_id = ?, data_key = ? WHERE id = ?", u.keyID, u.wrappedKey, u.id); err != nil {
+			return 0, fmt.Errorf("failed to re-wrap audit event data key: %v", err)
+		}
+	}
+	for _, u := range sealed {
+		_, err := tx.Exec("UPDATE audit_events SET extra_msg = ?, metadata = ?, key_id = ?, data_key = ? WHERE id = ? AND key_id IS NULL",
+			u.extraMsg, u.metadata, u.keyID, u.wrappedKey, u.id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt audit event: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit audit event re-encryption: %v", err)
+	}
+	l.RequireEncryption()
+	return len(updates) + len(sealed), nil
+}
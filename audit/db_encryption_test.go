@@ -0,0 +1,144 @@
+// audit/db_encryption_test.go
+package audit
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDBColumnEncryption(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "audit.db")
+
+	// A table created before column encryption existed is migrated on open
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	_, err = db.Exec(`CREATE TABLE audit_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT NOT NULL,
+		action_string TEXT NOT NULL,
+		extra_msg TEXT,
+		epoch_timestamp_sec INTEGER NOT NULL,
+		org_id INTEGER NOT NULL,
+		metadata TEXT
+	);
+	INSERT INTO audit_events (username, action_string, extra_msg, epoch_timestamp_sec, org_id, metadata)
+	VALUES ('alice', 'legacy', 'legacy-note', 100, 1, '{"ip":"10.0.0.1"}')`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+
+	k1, _ := NewKeyRing("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	logger, err := NewEncryptedDBAuditLogger(dbPath, k1)
+	if err != nil {
+		t.Fatalf("Failed to open encrypted DB audit logger: %v", err)
+	}
+	defer func() { logger.Close() }()
+	logger.CreateAuditEvent("bob", "login", "secret-note", 200, 1, map[string]interface{}{"ip": "10.0.0.2"})
+
+	// Sensitive columns are stored encrypted; the rest stay searchable in clear
+	var extraMsg, metadata string
+	row := logger.db.QueryRow("SELECT extra_msg, metadata FROM audit_events WHERE username = 'bob' AND action_string = 'login'")
+	if err := row.Scan(&extraMsg, &metadata); err != nil {
+		t.Fatalf("Failed to query encrypted row by clear columns: %v", err)
+	}
+	if strings.Contains(extraMsg, "secret") || strings.Contains(metadata, "10.0.0.2") {
+		t.Fatalf("Sensitive columns stored in clear: %q %q", extraMsg, metadata)
+	}
+
+	events, err := logger.ReadAuditEvents(1, 0, 0)
+	if err != nil || len(events) != 2 || events[1].ExtraMsg != "secret-note" {
+		t.Fatalf("Unexpected events after encryption: %+v (%v)", events, err)
+	}
+
+	// Rotate to k2 and re-encrypt: one row re-wrapped, one legacy row encrypted
+	rotated, _ := NewKeyRing("k2", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32), "k2": bytes.Repeat([]byte{2}, 32)})
+	logger = reopenEncryptedDB(t, logger, dbPath, rotated)
+	n, err := logger.ReencryptAuditEvents()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 rows re-encrypted, got %d (%v)", n, err)
+	}
+
+	current, _ := NewKeyRing("k2", map[string][]byte{"k2": bytes.Repeat([]byte{2}, 32)})
+	logger = reopenEncryptedDB(t, logger, dbPath, current)
+	events, err = logger.ReadAuditEvents(1, 0, 0)
+	if err != nil || len(events) != 2 || events[0].ExtraMsg != "legacy-note" || events[1].ExtraMsg != "secret-note" {
+		t.Fatalf("Unexpected events after rotation: %+v (%v)", events, err)
+	}
+
+	// Moving an encrypted row to another org is detected
+	if _, err := logger.db.Exec("UPDATE audit_events SET org_id = 2 WHERE username = 'bob'"); err != nil {
+		t.Fatalf("Failed to tamper with row: %v", err)
+	}
+	if _, err := logger.ReadAuditEvents(2, 0, 0); !errors.Is(err, ErrRecordDecryption) {
+		t.Errorf("Expected ErrRecordDecryption for a moved row, got %v", err)
+	}
+}
+
+func TestDBRejectsPlaintextRows(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "audit.db")
+	keys, _ := NewKeyRing("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	logger, err := NewEncryptedDBAuditLogger(dbPath, keys)
+	if err != nil {
+		t.Fatalf("Failed to open encrypted DB audit logger: %v", err)
+	}
+	defer func() { logger.Close() }()
+	logger.CreateAuditEvent("alice", "login", "note", 100, 1, nil)
+	if _, err := logger.ReencryptAuditEvents(); err != nil {
+		t.Fatalf("Failed to re-encrypt: %v", err)
+	}
+
+	// A row inserted in clear by someone without the key is a forgery
+	_, err = logger.db.Exec(`INSERT INTO audit_events (username, action_string, extra_msg, epoch_timestamp_sec, org_id)
+		VALUES ('mallory', 'login', 'forged', 200, 1)`)
+	if err != nil {
+		t.Fatalf("Failed to insert plaintext row: %v", err)
+	}
+	if _, err := logger.ReadAuditEvents(1, 0, 0); !errors.Is(err, ErrRecordDecryption) || !strings.Contains(err.Error(), "audit event 2") {
+		t.Fatalf("Expected ErrRecordDecryption naming the row after re-encryption, got %v", err)
+	}
+
+	// A reopened logger trusts it until encryption is required through the config
+	logger = reopenEncryptedDB(t, logger, dbPath, keys)
+	if events, err := logger.ReadAuditEvents(1, 0, 0); err != nil || len(events) != 2 {
+		t.Fatalf("Expected 2 events before encryption is required, got %d (%v)", len(events), err)
+	}
+	logger.Close()
+
+	configured, err := NewAuditLogger(DBLoggerType, map[string]string{
+		"dbPath":            dbPath,
+		"activeKeyId":       "k1",
+		"key.k1":            base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32)),
+		"requireEncryption": "true",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger from map config: %v", err)
+	}
+	logger = configured.(*DBAuditLogger)
+	if _, err := logger.ReadAuditEvents(1, 0, 0); !errors.Is(err, ErrRecordDecryption) {
+		t.Fatalf("Expected ErrRecordDecryption with requireEncryption, got %v", err)
+	}
+	if _, err := logger.ReencryptAuditEvents(); !errors.Is(err, ErrRecordDecryption) {
+		t.Fatalf("Expected re-encryption to refuse the plaintext row, got %v", err)
+	}
+}
+
+// reopenEncryptedDB closes logger and opens the database at dbPath again with keys
+func reopenEncryptedDB(t *testing.T, logger *DBAuditLogger, dbPath string, keys *KeyRing) *DBAuditLogger {
+	t.Helper()
+	if err := logger.Close(); err != nil {
+		t.Fatalf("Failed to close DB audit logger: %v", err)
+	}
+	reopened, err := NewEncryptedDBAuditLogger(dbPath, keys)
+	if err != nil {
+		t.Fatalf("Failed to reopen encrypted DB audit logger: %v", err)
+	}
+	return reopened
+}
@@ -2,7 +2,10 @@
 package audit
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -10,70 +13,230 @@ import (
 type LoggerType string
 
 const (
-	FileLoggerType LoggerType = "file"
-	DBLoggerType   LoggerType = "db"
+	FileLoggerType   LoggerType = "file"
+	DBLoggerType     LoggerType = "db"
+	MemoryLoggerType LoggerType = "memory"
 )
 
+// DefaultLoggerName is the name of the logger used by the package-level convenience functions
+const DefaultLoggerName = "default"
+
 var (
-	loggerInstance AuditLogger
-	loggerMutex    sync.Mutex
+	loggers     = make(map[string]*loggerEntry)
+	loggerMutex sync.Mutex
 )
 
-// InitAuditLogger initializes the audit logger with the specified type and configuration
+// loggerEntry is a registered logger together with the convenience calls still using it
+type loggerEntry struct {
+	logger   AuditLogger
+	inflight sync.WaitGroup
+}
+
+// NewAuditLogger creates an audit logger of the specified registered type without registering it
+func NewAuditLogger(loggerType LoggerType, config map[string]string) (AuditLogger, error) {
+	b, ok := lookupBackend(loggerType)
+	if !ok {
+		return nil, fmt.Errorf("unsupported logger type: %s", loggerType)
+	}
+
+	return b.constructor(config)
+}
+
+// InitAuditLogger initializes the default audit logger with the specified type and configuration
 func InitAuditLogger(loggerType LoggerType, config map[string]string) error {
+	return InitNamedAuditLogger(DefaultLoggerName, loggerType, config)
+}
+
+// InitNamedAuditLogger initializes the audit logger registered under name
+func InitNamedAuditLogger(name string, loggerType LoggerType, config map[string]string) error {
+	logger, err := NewAuditLogger(loggerType, config)
+	if err != nil {
+		return err
+	}
+
+	return RegisterAuditLogger(name, logger)
+}
+
+// RegisterAuditLogger registers an existing logger under name. A logger previously
+// registered under the same name is drained of in-flight convenience calls, flushed
+// and closed; the new logger is installed even if closing the old one fails.
+func RegisterAuditLogger(name string, logger AuditLogger) error {
+	if name == "" {
+		return fmt.Errorf("audit logger name must not be empty")
+	}
+	if logger == nil {
+		return fmt.Errorf("audit logger %q must not be nil", name)
+	}
+
+	loggerMutex.Lock()
+	old := loggers[name]
+	loggers[name] = &loggerEntry{logger: logger}
+	retire := old != nil && old.logger != logger && !isRegisteredLocked(old.logger)
+	loggerMutex.Unlock()
+
+	if !retire {
+		return nil
+	}
+	if err := old.retire(); err != nil {
+		return fmt.Errorf("failed to close previous audit logger %q: %w", name, err)
+	}
+	return nil
+}
+
+// UnregisterAuditLogger removes the logger registered under name and returns it without closing it
+func UnregisterAuditLogger(name string) (AuditLogger, bool) {
 	loggerMutex.Lock()
 	defer loggerMutex.Unlock()
 
-	var err error
+	entry, ok := loggers[name]
+	if !ok {
+		return nil, false
+	}
+	delete(loggers, name)
+	return entry.logger, true
+}
+
+// ShutdownAuditLogger unregisters every logger, waits for in-flight convenience calls,
+// then flushes and closes each one. It returns ctx.Err() if ctx ends first; the
+// remaining loggers keep closing in the background.
+func ShutdownAuditLogger(ctx context.Context) error {
+	// A logger registered under several names is closed once, after the calls made
+	// through every one of its names have finished
+	loggerMutex.Lock()
+	var order []AuditLogger
+	entries := make(map[AuditLogger][]*loggerEntry)
+	for name, entry := range loggers {
+		delete(loggers, name)
+		if entries[entry.logger] == nil {
+			order = append(order, entry.logger)
+		}
+		entries[entry.logger] = append(entries[entry.logger], entry)
+	}
+	loggerMutex.Unlock()
 
-	switch loggerType {
-	case FileLoggerType:
-		filePath, ok := config["filePath"]
-		if !ok {
-			return fmt.Errorf("file path not provided for file logger")
+	done := make(chan error, 1)
+	go func() {
+		var errs []error
+		for _, logger := range order {
+			if err := retireLogger(logger, entries[logger]...); err != nil {
+				errs = append(errs, err)
+			}
 		}
-		loggerInstance, err = NewFileAuditLogger(filePath)
-	case DBLoggerType:
-		dbPath, ok := config["dbPath"]
-		if !ok {
-			return fmt.Errorf("database path not provided for DB logger")
+		done <- errors.Join(errs...)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("audit logger shutdown interrupted: %w", ctx.Err())
+	}
+}
+
+// retire waits for in-flight convenience calls, then flushes and closes the logger
+func (e *loggerEntry) retire() error {
+	return retireLogger(e.logger, e)
+}
+
+// retireLogger waits for the in-flight convenience calls of every entry holding
+// logger, then flushes and closes it
+func retireLogger(logger AuditLogger, entries ...*loggerEntry) error {
+	for _, entry := range entries {
+		entry.inflight.Wait()
+	}
+	flushErr := logger.Flush()
+	if errors.Is(flushErr, ErrLoggerClosed) {
+		flushErr = nil
+	}
+	return errors.Join(flushErr, logger.Close())
+}
+
+// isRegisteredLocked reports whether logger is registered under any name; callers must hold loggerMutex
+func isRegisteredLocked(logger AuditLogger) bool {
+	for _, entry := range loggers {
+		if entry.logger == logger {
+			return true
 		}
-		loggerInstance, err = NewDBAuditLogger(dbPath)
-	default:
-		return fmt.Errorf("unsupported logger type: %s", loggerType)
 	}
+	return false
+}
+
+// useLogger runs fn with the logger registered under name, keeping the logger
+// from being closed by re-initialization until fn returns
+func useLogger(name string, fn func(AuditLogger) error) error {
+	loggerMutex.Lock()
+	entry, ok := loggers[name]
+	if ok {
+		entry.inflight.Add(1)
+	}
+	loggerMutex.Unlock()
+
+	if !ok {
+		return notInitializedError(name)
+	}
+	defer entry.inflight.Done()
+
+	return fn(entry.logger)
+}
 
-	return err
+// notInitializedError describes a lookup of a logger that was never registered
+func notInitializedError(name string) error {
+	if name == DefaultLoggerName {
+		return fmt.Errorf("audit logger not initialized, call InitAuditLogger first")
+	}
+	return fmt.Errorf("audit logger %q not initialized", name)
 }
 
-// GetAuditLogger returns the initialized audit logger
+// GetAuditLogger returns the initialized default audit logger
 func GetAuditLogger() (AuditLogger, error) {
+	return GetNamedAuditLogger(DefaultLoggerName)
+}
+
+// GetNamedAuditLogger returns the audit logger registered under name. Loggers obtained
+// this way are not tracked when the logger is re-initialized or shut down.
+func GetNamedAuditLogger(name string) (AuditLogger, error) {
 	loggerMutex.Lock()
 	defer loggerMutex.Unlock()
 
-	if loggerInstance == nil {
-		return nil, fmt.Errorf("audit logger not initialized, call InitAuditLogger first")
+	entry, ok := loggers[name]
+	if !ok {
+		return nil, notInitializedError(name)
 	}
 
-	return loggerInstance, nil
+	return entry.logger, nil
 }
 
-// CreateAuditEvent is a convenience function to create an audit event without getting the logger
-func CreateAuditEvent(username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
-	logger, err := GetAuditLogger()
-	if err != nil {
-		return err
+// AuditLoggerNames returns the names of all registered loggers in sorted order
+func AuditLoggerNames() []string {
+	loggerMutex.Lock()
+	defer loggerMutex.Unlock()
+
+	names := make([]string, 0, len(loggers))
+	for name := range loggers {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	return logger.CreateAuditEvent(username, actionString, extraMsg, epochTimestampSec, orgID, metadata)
+// CreateAuditEvent is a convenience function to create an audit event without getting the logger
+func CreateAuditEvent(username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
+	return useLogger(DefaultLoggerName, func(logger AuditLogger) error {
+		return logger.CreateAuditEvent(username, actionString, extraMsg, epochTimestampSec, orgID, metadata)
+	})
 }
 
 // ReadAuditEvents is a convenience function to read audit events without getting the logger
 func ReadAuditEvents(orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
-	logger, err := GetAuditLogger()
+	var events []AuditEvent
+	err := useLogger(DefaultLoggerName, func(logger AuditLogger) error {
+		var err error
+		events, err = logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
-}
\ No newline at end of file
+	return events, nil
+}
@@ -0,0 +1,147 @@
+// audit/keyring.go
+package audit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ErrRecordDecryption is returned when an encrypted audit record cannot be decrypted,
+// either because its key is unknown or because the record has been tampered with
+var ErrRecordDecryption = errors.New("failed to decrypt audit record")
+
+// KeyRing holds the keys used to encrypt audit records at rest. New records are
+// encrypted with the active key; older keys are kept so existing records stay readable.
+type KeyRing struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+// keyRingFile is the on-disk form of a KeyRing, with keys encoded as standard base64
+type keyRingFile struct {
+	ActiveKeyID string            `json:"activeKeyId"`
+	Keys        map[string]string `json:"keys"`
+}
+
+// NewKeyRing creates a KeyRing from 32-byte AES keys indexed by key ID
+func NewKeyRing(activeID string, keys map[string][]byte) (*KeyRing, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("key ring has no keys")
+	}
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("active key %q not found in key ring", activeID)
+	}
+
+	ring := &KeyRing{activeID: activeID, keys: make(map[string][]byte, len(keys))}
+	for id, key := range keys {
+		if id == "" {
+			return nil, fmt.Errorf("key ring contains a key with an empty ID")
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+		ring.keys[id] = append([]byte(nil), key...)
+	}
+	return ring, nil
+}
+
+// NewKeyRingFromBase64 creates a KeyRing from base64-encoded keys indexed by key ID
+func NewKeyRingFromBase64(activeID string, encoded map[string]string) (*KeyRing, error) {
+	keys := make(map[string][]byte, len(encoded))
+	for id, value := range encoded {
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not valid base64: %v", id, err)
+		}
+		keys[id] = key
+	}
+	return NewKeyRing(activeID, keys)
+}
+
+// LoadKeyRing reads a key file of the form {"activeKeyId": "...", "keys": {"<id>": "<base64 key>"}}
+func LoadKeyRing(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit key file: %v", err)
+	}
+
+	var file keyRingFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse audit key file %s: %v", path, err)
+	}
+
+	ring, err := NewKeyRingFromBase64(file.ActiveKeyID, file.Keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit key file %s: %v", path, err)
+	}
+	return ring, nil
+}
+
+// ActiveKeyID returns the ID of the key used for new records
+func (k *KeyRing) ActiveKeyID() string {
+	return k.activeID
+}
+
+// KeyIDs returns the IDs of every key in the ring, sorted
+func (k *KeyRing) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// keyRingSource is implemented by loggers that encrypt what they store
+type keyRingSource interface {
+	KeyRing() *KeyRing
+}
+
+// keyRingOf returns the key ring of logger, or nil if it stores plaintext
+func keyRingOf(logger AuditLogger) *KeyRing {
+	source, ok := logger.(keyRingSource)
+	if !ok {
+		return nil
+	}
+	return source.KeyRing()
+}
+
+// encryptedRecord is a line of an encrypted audit log file
+type encryptedRecord struct {
+	KeyID      string `json:"keyId"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Purposes bound into the additional data of everything sealed with a key ring key,
+// so a ciphertext made for one purpose cannot be substituted for another
+const (
+	purposeRecord  = "audit-record"
+	purposeDataKey = "audit-data-key"
+	purposeArchive = "audit-archive"
+)
+
+// encrypt seals plaintext for purpose with the active key
+func (k *KeyRing) encrypt(purpose string, plaintext []byte) (encryptedRecord, error) {
+	ciphertext, err := seal(k.keys[k.activeID], plaintext, []byte(purpose+"|"+k.activeID))
+	if err != nil {
+		return encryptedRecord{}, err
+	}
+	return encryptedRecord{KeyID: k.activeID, Ciphertext: ciphertext}, nil
+}
+
+// decrypt opens a record sealed for purpose with any key in the ring
+func (k *KeyRing) decrypt(purpose string, record encryptedRecord) ([]byte, error) {
+	key, ok := k.keys[record.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrRecordDecryption, record.KeyID)
+	}
+	plaintext, err := open(key, record.Ciphertext, []byte(purpose+"|"+record.KeyID))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRecordDecryption, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,204 @@
+// audit/keyring_test.go
+package audit_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+// testKey returns a deterministic 32-byte key
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestEncryptedFileAuditLoggerConformance(t *testing.T) {
+	keys, err := audit.NewKeyRing("k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("Failed to create key ring: %v", err)
+	}
+	audittest.RunConformance(t, func(t *testing.T) audit.AuditLogger {
+		logger, err := audit.NewEncryptedFileAuditLogger(filepath.Join(t.TempDir(), "audit.log"), keys)
+		if err != nil {
+			t.Fatalf("Failed to create encrypted file audit logger: %v", err)
+		}
+		return logger
+	})
+}
+
+func TestEncryptedDBAuditLoggerConformance(t *testing.T) {
+	keys, err := audit.NewKeyRing("k1", map[string][]byte{"k1": testKey(1)})
+	if err != nil {
+		t.Fatalf("Failed to create key ring: %v", err)
+	}
+	audittest.RunConformance(t, func(t *testing.T) audit.AuditLogger {
+		logger, err := audit.NewEncryptedDBAuditLogger(filepath.Join(t.TempDir(), "audit.db"), keys)
+		if err != nil {
+			t.Fatalf("Failed to create encrypted DB audit logger: %v", err)
+		}
+		t.Cleanup(func() { logger.Close() })
+		return logger
+	})
+}
+
+func TestEncryptedFileKeyRotation(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+
+	// Start with a plaintext log, then switch to encryption
+	plain, err := audit.NewFileAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to create file audit logger: %v", err)
+	}
+	plain.CreateAuditEvent("alice", audit.ActionUserLogin, "plaintext", 100, 1, nil)
+
+	oldKeys, _ := audit.NewKeyRing("k1", map[string][]byte{"k1": testKey(1)})
+	logger, err := audit.NewEncryptedFileAuditLogger(logPath, oldKeys)
+	if err != nil {
+		t.Fatalf("Failed to create encrypted file audit logger: %v", err)
+	}
+	logger.CreateAuditEvent("bob", audit.ActionUserLogin, "secret-note", 200, 1, nil)
+
+	data, _ := os.ReadFile(logPath)
+	if bytes.Contains(data, []byte("secret-note")) || bytes.Contains(data, []byte("bob")) {
+		t.Fatalf("Encrypted record stored in clear: %s", data)
+	}
+
+	// Rotate: new records use k2, old records stay readable
+	rotated, _ := audit.NewKeyRing("k2", map[string][]byte{"k1": testKey(1), "k2": testKey(2)})
+	logger, _ = audit.NewEncryptedFileAuditLogger(logPath, rotated)
+	logger.CreateAuditEvent("carol", audit.ActionUserLogin, "", 300, 1, nil)
+	audittest.ExpectEventCount(t, readAll{logger}, audittest.Match{OrgID: 1}, 3)
+
+	n, err := logger.ReencryptAuditEvents()
+	if err != nil || n != 2 {
+		t.Fatalf("Expected 2 records re-encrypted, got %d (%v)", n, err)
+	}
+
+	// After re-encryption the retired key can be dropped
+	current, _ := audit.NewKeyRing("k2", map[string][]byte{"k2": testKey(2)})
+	logger, _ = audit.NewEncryptedFileAuditLogger(logPath, current)
+	audittest.ExpectEventCount(t, readAll{logger}, audittest.Match{OrgID: 1}, 3)
+	audittest.ExpectEvent(t, readAll{logger}, audittest.Match{Username: "alice", ExtraMsgContains: "plaintext"})
+
+	// Without a key the log cannot be read
+	if _, err := plain.ReadAuditEvents(1, 0, 0); !errors.Is(err, audit.ErrRecordDecryption) {
+		t.Errorf("Expected ErrRecordDecryption without a key ring, got %v", err)
+	}
+}
+
+func TestEncryptedFileTamperDetection(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	keys, _ := audit.NewKeyRing("k1", map[string][]byte{"k1": testKey(1)})
+	logger, _ := audit.NewEncryptedFileAuditLogger(logPath, keys)
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 100, 1, nil)
+
+	// Claim the record was sealed with a different key ID
+	data, _ := os.ReadFile(logPath)
+	data = bytes.Replace(data, []byte(`"keyId":"k1"`), []byte(`"keyId":"k2"`), 1)
+	os.WriteFile(logPath, data, 0644)
+
+	other, _ := audit.NewKeyRing("k1", map[string][]byte{"k1": testKey(1), "k2": testKey(1)})
+	logger, _ = audit.NewEncryptedFileAuditLogger(logPath, other)
+	if _, err := logger.ReadAuditEvents(1, 0, 0); !errors.Is(err, audit.ErrRecordDecryption) {
+		t.Errorf("Expected ErrRecordDecryption for a tampered record, got %v", err)
+	}
+}
+
+func TestEncryptedFileConfig(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "keys.json")
+	encoded := base64.StdEncoding.EncodeToString(testKey(7))
+	os.WriteFile(keyFile, []byte(`{"activeKeyId": "2026-10", "keys": {"2026-10": "`+encoded+`"}}`), 0600)
+
+	logPath := filepath.Join(dir, "audit.log")
+	logger, err := audit.NewAuditLoggerFromConfig(audit.Config{
+		Type: audit.FileLoggerType,
+		File: &audit.FileLoggerConfig{FilePath: logPath, EncryptionConfig: audit.EncryptionConfig{KeyFile: keyFile}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger from config: %v", err)
+	}
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 100, 1, nil)
+
+	// The same key given inline through the map form reads the record back
+	logger, err = audit.NewAuditLogger(audit.FileLoggerType, map[string]string{
+		"filePath":    logPath,
+		"activeKeyId": "2026-10",
+		"key.2026-10": encoded,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger from map config: %v", err)
+	}
+	audittest.ExpectAction(t, readAll{logger}, audit.ActionUserLogin, "alice")
+
+	err = audit.Config{
+		Type: audit.FileLoggerType,
+		File: &audit.FileLoggerConfig{FilePath: logPath, EncryptionConfig: audit.EncryptionConfig{KeyFile: keyFile, Keys: map[string]string{"a": encoded}, ActiveKeyID: "a"}},
+	}.Validate()
+	if err == nil {
+		t.Errorf("Expected keyFile and keys together to be rejected")
+	}
+}
+
+func TestEncryptedFileRejectsPlaintext(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.log")
+	keys, _ := audit.NewKeyRing("k1", map[string][]byte{"k1": testKey(1)})
+	logger, _ := audit.NewEncryptedFileAuditLogger(logPath, keys)
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 100, 1, nil)
+	if _, err := logger.ReencryptAuditEvents(); err != nil {
+		t.Fatalf("Failed to re-encrypt: %v", err)
+	}
+
+	// A plaintext record appended by someone without the key is a forgery
+	forger, _ := audit.NewFileAuditLogger(logPath)
+	forger.CreateAuditEvent("mallory", audit.ActionUserLogin, "", 200, 1, nil)
+	if _, err := logger.ReadAuditEvents(1, 0, 0); !errors.Is(err, audit.ErrRecordDecryption) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("Expected ErrRecordDecryption naming line 2 after re-encryption, got %v", err)
+	}
+
+	// A fresh logger trusts it until encryption is required through the config
+	logger, _ = audit.NewEncryptedFileAuditLogger(logPath, keys)
+	audittest.ExpectEventCount(t, readAll{logger}, audittest.Match{OrgID: 1}, 2)
+
+	configured, err := audit.NewAuditLoggerFromConfig(audit.Config{
+		Type: audit.FileLoggerType,
+		File: &audit.FileLoggerConfig{FilePath: logPath, EncryptionConfig: audit.EncryptionConfig{
+			Keys:              map[string]string{"k1": base64.StdEncoding.EncodeToString(testKey(1))},
+			ActiveKeyID:       "k1",
+			RequireEncryption: true,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger from config: %v", err)
+	}
+	if _, err := configured.ReadAuditEvents(1, 0, 0); !errors.Is(err, audit.ErrRecordDecryption) {
+		t.Fatalf("Expected ErrRecordDecryption with requireEncryption, got %v", err)
+	}
+
+	// Re-encryption does not launder the forged record
+	if _, err := configured.(*audit.FileAuditLogger).ReencryptAuditEvents(); !errors.Is(err, audit.ErrRecordDecryption) {
+		t.Fatalf("Expected re-encryption to refuse the plaintext record, got %v", err)
+	}
+
+	if _, err := audit.ConfigFromMap(audit.FileLoggerType, map[string]string{"filePath": logPath, "requireEncryption": "true"}); err == nil {
+		t.Error("Expected requireEncryption without keys to be rejected")
+	}
+}
+
+// readAll adapts an AuditLogger to audittest.EventSource for org 1
+type readAll struct {
+	logger audit.AuditLogger
+}
+
+func (r readAll) Events() []audit.AuditEvent {
+	events, _ := r.logger.ReadAuditEvents(1, 0, 0)
+	return events
+}
@@ -0,0 +1,295 @@
+// audit/legal_hold.go
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrLegalHold is returned when an operation would destroy data under an active legal hold
+var ErrLegalHold = errors.New("audit events are under legal hold")
+
+// LegalHold freezes deletion of audit events for an organization, a user, or a user within an organization
+type LegalHold struct {
+	ID string `json:"id"`
+
+	// OrgID limits the hold to one organization; 0 applies it to every organization
+	OrgID int64 `json:"orgId,omitempty"`
+
+	// Username limits the hold to one user; empty applies it to every user
+	Username string `json:"username,omitempty"`
+
+	Reason      string `json:"reason"`
+	PlacedBy    string `json:"placedBy"`
+	PlacedAtSec int64  `json:"placedAtSec"`
+}
+
+// Covers reports whether the hold applies to event
+func (h LegalHold) Covers(event AuditEvent) bool {
+	return (h.OrgID == 0 || h.OrgID == event.OrgID) &&
+		(h.Username == "" || h.Username == event.Username)
+}
+
+// LegalHoldRegistry keeps the active legal holds in a JSON file stored alongside the
+// audit logs and records every change as an audit event. The file is re-read on every
+// use, so holds placed by other processes are seen. The file and DB loggers enforce the
+// holds stored at LegalHoldPath of their own path in every purge.
+type LegalHoldRegistry struct {
+	path   string
+	logger AuditLogger
+	mu     sync.RWMutex
+	holds  map[string]LegalHold
+}
+
+// LegalHoldPath returns the default registry path for an audit log or database path
+func LegalHoldPath(logPath string) string {
+	return logPath + ".holds.json"
+}
+
+// NewLegalHoldRegistry loads the registry stored at path, creating it on first use.
+// Hold changes are recorded to logger.
+func NewLegalHoldRegistry(path string, logger AuditLogger) (*LegalHoldRegistry, error) {
+	if logger == nil {
+		return nil, fmt.Errorf("legal hold registry requires an audit logger")
+	}
+
+	r := &LegalHoldRegistry{
+		path:   path,
+		logger: logger,
+		holds:  make(map[string]LegalHold),
+	}
+	if err := r.loadLocked(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// loadLocked replaces the holds in memory with those stored in the registry file;
+// callers must hold r.mu for writing
+func (r *LegalHoldRegistry) loadLocked() error {
+	holds, err := readLegalHolds(r.path)
+	if err != nil {
+		return err
+	}
+	r.holds = make(map[string]LegalHold, len(holds))
+	for _, hold := range holds {
+		r.holds[hold.ID] = hold
+	}
+	return nil
+}
+
+// readLegalHolds reads the holds stored at path; a missing file holds none
+func readLegalHolds(path string) ([]LegalHold, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read legal hold registry: %v", err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var holds []LegalHold
+	if err := json.Unmarshal(data, &holds); err != nil {
+		return nil, fmt.Errorf("failed to parse legal hold registry: %v", err)
+	}
+	return holds, nil
+}
+
+// holdsCover reports whether any of holds covers event
+func holdsCover(holds []LegalHold, event AuditEvent) bool {
+	for _, hold := range holds {
+		if hold.Covers(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// legalHoldSource is implemented by loggers that enforce legal holds stored alongside their data
+type legalHoldSource interface {
+	LegalHolds() ([]LegalHold, error)
+}
+
+// legalHoldsOf returns the holds enforced by logger, or none if it keeps no holds
+func legalHoldsOf(logger AuditLogger) ([]LegalHold, error) {
+	source, ok := logger.(legalHoldSource)
+	if !ok {
+		return nil, nil
+	}
+	return source.LegalHolds()
+}
+
+// PlaceHold activates a hold and returns it with its ID and placement time filled in
+func (r *LegalHoldRegistry) PlaceHold(hold LegalHold) (LegalHold, error) {
+	if hold.OrgID == 0 && hold.Username == "" {
+		return hold, fmt.Errorf("legal hold must name an organization or a user")
+	}
+	if hold.PlacedBy == "" {
+		return hold, fmt.Errorf("legal hold must record who placed it")
+	}
+	if hold.ID == "" {
+		id, err := newHoldID()
+		if err != nil {
+			return hold, err
+		}
+		hold.ID = id
+	}
+	if hold.PlacedAtSec == 0 {
+		hold.PlacedAtSec = time.Now().Unix()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadLocked(); err != nil {
+		return hold, err
+	}
+	if _, exists := r.holds[hold.ID]; exists {
+		return hold, fmt.Errorf("legal hold %q already exists", hold.ID)
+	}
+
+	r.holds[hold.ID] = hold
+	if err := r.saveLocked(); err != nil {
+		delete(r.holds, hold.ID)
+		return hold, err
+	}
+
+	err := r.logger.CreateAuditEvent(
+		hold.PlacedBy,
+		ActionLegalHoldPlace,
+		fmt.Sprintf("Placed legal hold %s: %s", hold.ID, hold.Reason),
+		hold.PlacedAtSec,
+		hold.OrgID,
+		holdMetadata(hold),
+	)
+	if err != nil {
+		return hold, fmt.Errorf("legal hold placed but not audited: %w", err)
+	}
+
+	return hold, nil
+}
+
+// ReleaseHold deactivates the hold with the given ID
+func (r *LegalHoldRegistry) ReleaseHold(id, releasedBy string) error {
+	if releasedBy == "" {
+		return fmt.Errorf("legal hold release must record who released it")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.loadLocked(); err != nil {
+		return err
+	}
+	hold, ok := r.holds[id]
+	if !ok {
+		return fmt.Errorf("legal hold %q not found", id)
+	}
+
+	delete(r.holds, id)
+	if err := r.saveLocked(); err != nil {
+		r.holds[id] = hold
+		return err
+	}
+
+	err := r.logger.CreateAuditEvent(
+		releasedBy,
+		ActionLegalHoldRelease,
+		fmt.Sprintf("Released legal hold %s", hold.ID),
+		time.Now().Unix(),
+		hold.OrgID,
+		holdMetadata(hold),
+	)
+	if err != nil {
+		return fmt.Errorf("legal hold released but not audited: %w", err)
+	}
+
+	return nil
+}
+
+// Holds returns the active holds ordered by placement time. If the registry file
+// cannot be read, the holds last read are returned so a hold is never dropped.
+func (r *LegalHoldRegistry) Holds() []LegalHold {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loadLocked()
+	return r.sortedLocked()
+}
+
+// IsHeld reports whether any active hold covers event. Use Holds to check many events.
+func (r *LegalHoldRegistry) IsHeld(event AuditEvent) bool {
+	return holdsCover(r.Holds(), event)
+}
+
+func (r *LegalHoldRegistry) sortedLocked() []LegalHold {
+	holds := make([]LegalHold, 0, len(r.holds))
+	for _, hold := range r.holds {
+		holds = append(holds, hold)
+	}
+	sort.Slice(holds, func(i, j int) bool {
+		if holds[i].PlacedAtSec != holds[j].PlacedAtSec {
+			return holds[i].PlacedAtSec < holds[j].PlacedAtSec
+		}
+		return holds[i].ID < holds[j].ID
+	})
+	return holds
+}
+
+// saveLocked atomically writes the registry file; callers must hold r.mu
+func (r *LegalHoldRegistry) saveLocked() error {
+	data, err := json.MarshalIndent(r.sortedLocked(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal legal holds: %v", err)
+	}
+
+	return writeFileAtomic(r.path, data, 0600)
+}
+
+// holdMetadata describes a hold in audit event metadata
+func holdMetadata(hold LegalHold) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"holdId": hold.ID,
+		"reason": hold.Reason,
+	}
+	if hold.Username != "" {
+		metadata["heldUsername"] = hold.Username
+	}
+	return metadata
+}
+
+// newHoldID returns a random hold identifier
+func newHoldID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate legal hold ID: %v", err)
+	}
+	return "hold-" + hex.EncodeToString(buf), nil
+}
+
+// PurgeAuditEvents deletes the events matching shouldPurge from logger, except those
+// covered by an active legal hold. The file and DB loggers always enforce the holds
+// stored next to them; holds adds the holds of another registry and may be nil.
+func PurgeAuditEvents(logger AuditLogger, holds *LegalHoldRegistry, shouldPurge func(AuditEvent) bool) (map[int64]int, error) {
+	purger, ok := logger.(AuditPurger)
+	if !ok {
+		return nil, fmt.Errorf("audit logger %T does not support purging", logger)
+	}
+
+	var active []LegalHold
+	if holds != nil {
+		active = holds.Holds()
+	}
+	return purger.PurgeAuditEvents(func(event AuditEvent) bool {
+		return !holdsCover(active, event) && shouldPurge(event)
+	})
+}
@@ -0,0 +1,127 @@
+// audit/legal_hold_test.go
+package audit_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestLegalHoldBlocksRetention(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.log")
+	logger, err := audit.NewFileAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to create file audit logger: %v", err)
+	}
+	holdLog := audit.NewMemoryAuditLogger(0)
+
+	holds, err := audit.NewLegalHoldRegistry(audit.LegalHoldPath(logPath), holdLog)
+	if err != nil {
+		t.Fatalf("Failed to create legal hold registry: %v", err)
+	}
+	orgHold, err := holds.PlaceHold(audit.LegalHold{OrgID: 1, Reason: "Case 42", PlacedBy: "counsel"})
+	if err != nil {
+		t.Fatalf("Failed to place org hold: %v", err)
+	}
+	if _, err := holds.PlaceHold(audit.LegalHold{Username: "mallory", Reason: "Case 43", PlacedBy: "counsel"}); err != nil {
+		t.Fatalf("Failed to place user hold: %v", err)
+	}
+	audittest.ExpectEventCount(t, holdLog, audittest.Match{Username: "counsel", ActionString: audit.ActionLegalHoldPlace}, 2)
+
+	old := time.Now().Add(-48 * time.Hour).Unix()
+	logger.CreateAuditEvent("alice", audit.ActionIndexDelete, "", old, 1, nil)
+	logger.CreateAuditEvent("bob", audit.ActionIndexDelete, "", old, 2, nil)
+	logger.CreateAuditEvent("mallory", audit.ActionIndexDelete, "", old, 2, nil)
+
+	job := &audit.RetentionJob{
+		Logger: logger,
+		Policy: audit.RetentionPolicy{Default: time.Hour},
+		Holds:  holds,
+	}
+	deleted, err := job.RunOnce(time.Now())
+	if err != nil {
+		t.Fatalf("Retention run failed: %v", err)
+	}
+	if deleted[1] != 0 || deleted[2] != 1 {
+		t.Fatalf("Expected only bob's event to be purged, got %v", deleted)
+	}
+
+	// Holds persist across registry instances
+	reloaded, err := audit.NewLegalHoldRegistry(audit.LegalHoldPath(logPath), holdLog)
+	if err != nil {
+		t.Fatalf("Failed to reload legal hold registry: %v", err)
+	}
+	if len(reloaded.Holds()) != 2 {
+		t.Fatalf("Expected 2 persisted holds, got %d", len(reloaded.Holds()))
+	}
+
+	if err := reloaded.ReleaseHold(orgHold.ID, "counsel"); err != nil {
+		t.Fatalf("Failed to release hold: %v", err)
+	}
+	audittest.ExpectAction(t, holdLog, audit.ActionLegalHoldRelease, "counsel")
+
+	deleted, err = audit.PurgeAuditEvents(logger, reloaded, func(audit.AuditEvent) bool { return true })
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if deleted[1] != 1 {
+		t.Fatalf("Expected released org events to be purgeable, got %v", deleted)
+	}
+
+	events, _ := logger.ReadAuditEvents(2, 0, 0)
+	if len(events) != 1 || events[0].Username != "mallory" {
+		t.Fatalf("Expected mallory's held event to survive, got %+v", events)
+	}
+
+	if _, err := holds.PlaceHold(audit.LegalHold{PlacedBy: "counsel"}); err == nil {
+		t.Fatal("Expected error for hold without org or user")
+	}
+}
+
+func TestLegalHoldEnforcedByLogger(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.log")
+	logger, err := audit.NewFileAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to create file audit logger: %v", err)
+	}
+	holdLog := audit.NewMemoryAuditLogger(0)
+
+	// Both registries are opened before any hold exists
+	first, err := audit.NewLegalHoldRegistry(audit.LegalHoldPath(logPath), holdLog)
+	if err != nil {
+		t.Fatalf("Failed to create legal hold registry: %v", err)
+	}
+	second, err := audit.NewLegalHoldRegistry(audit.LegalHoldPath(logPath), holdLog)
+	if err != nil {
+		t.Fatalf("Failed to create legal hold registry: %v", err)
+	}
+	if _, err := second.PlaceHold(audit.LegalHold{Username: "mallory", Reason: "Case 44", PlacedBy: "counsel"}); err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+
+	mallory := audit.AuditEvent{Username: "mallory", OrgID: 1}
+	if !first.IsHeld(mallory) {
+		t.Fatal("Expected a hold placed through another registry to be visible")
+	}
+
+	logger.CreateAuditEvent("alice", audit.ActionIndexDelete, "", 1, 1, nil)
+	logger.CreateAuditEvent("mallory", audit.ActionIndexDelete, "", 1, 1, nil)
+
+	// Calling the backend directly still respects the hold
+	deleted, err := logger.PurgeAuditEvents(func(audit.AuditEvent) bool { return true })
+	if err != nil {
+		t.Fatalf("Purge failed: %v", err)
+	}
+	if deleted[1] != 1 {
+		t.Fatalf("Expected only alice's event to be purged, got %v", deleted)
+	}
+	events, _ := logger.ReadAuditEvents(1, 0, 0)
+	if len(events) != 1 || events[0].Username != "mallory" {
+		t.Fatalf("Expected mallory's held event to survive, got %+v", events)
+	}
+}
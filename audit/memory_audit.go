@@ -0,0 +1,220 @@
+// audit/memory_audit.go
+package audit
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// MemoryAuditLogger implements AuditLogger interface using in-memory storage.
+// With a positive capacity it acts as a ring buffer that keeps only the newest events.
+type MemoryAuditLogger struct {
+	mu       sync.RWMutex
+	events   []AuditEvent
+	capacity int
+	next     int
+	dropped  int64
+	closed   bool
+}
+
+// NewMemoryAuditLogger creates a new MemoryAuditLogger; a capacity of 0 means unbounded
+func NewMemoryAuditLogger(capacity int) *MemoryAuditLogger {
+	if capacity < 0 {
+		capacity = 0
+	}
+	return &MemoryAuditLogger{
+		capacity: capacity,
+	}
+}
+
+// CreateAuditEvent stores a user action in memory
+func (l *MemoryAuditLogger) CreateAuditEvent(username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
+	// If timestamp is 0, use current time
+	if epochTimestampSec == 0 {
+		epochTimestampSec = time.Now().Unix()
+	}
+
+	// Keep metadata encoded so reads follow the same round-trip contract as persistent backends
+	metadataJSON, err := encodeMetadata(metadata)
+	if err != nil {
+		return err
+	}
+
+	event := AuditEvent{
+		Username:          username,
+		ActionString:      actionString,
+		ExtraMsg:          extraMsg,
+		EpochTimestampSec: epochTimestampSec,
+		OrgID:             orgID,
+	}
+	if metadataJSON != nil {
+		event.Metadata = json.RawMessage(metadataJSON)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return ErrLoggerClosed
+	}
+
+	if l.capacity == 0 || len(l.events) < l.capacity {
+		l.events = append(l.events, event)
+		return nil
+	}
+
+	// Buffer is full, overwrite the oldest event
+	l.events[l.next] = event
+	l.next = (l.next + 1) % l.capacity
+	l.dropped++
+	return nil
+}
+
+// ReadAuditEvents reads audit events from memory for a specific organization and time range
+func (l *MemoryAuditLogger) ReadAuditEvents(orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	if l.isClosed() {
+		return nil, ErrLoggerClosed
+	}
+
+	events, err := l.filter(func(event AuditEvent) bool {
+		return event.OrgID == orgID &&
+			event.EpochTimestampSec >= startEpochSec &&
+			(endEpochSec == 0 || event.EpochTimestampSec <= endEpochSec)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortEvents(events)
+
+	return events, nil
+}
+
+// Flush is a no-op because events are stored synchronously
+func (l *MemoryAuditLogger) Flush() error {
+	if l.isClosed() {
+		return ErrLoggerClosed
+	}
+	return nil
+}
+
+// Close rejects further writes and reads; stored events remain available through Events
+func (l *MemoryAuditLogger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	return nil
+}
+
+func (l *MemoryAuditLogger) isClosed() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.closed
+}
+
+// ScanAuditEvents calls fn for every stored event in insertion order
+func (l *MemoryAuditLogger) ScanAuditEvents(fn func(AuditEvent) error) error {
+	if l.isClosed() {
+		return ErrLoggerClosed
+	}
+
+	events, err := l.filter(nil)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := fn(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PurgeAuditEvents removes the events matching shouldPurge from memory
+func (l *MemoryAuditLogger) PurgeAuditEvents(shouldPurge func(AuditEvent) bool) (map[int64]int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, ErrLoggerClosed
+	}
+
+	deleted := make(map[int64]int)
+	kept := make([]AuditEvent, 0, len(l.events))
+	for i := 0; i < len(l.events); i++ {
+		stored := l.events[(l.next+i)%len(l.events)]
+		event := stored
+		if raw, ok := event.Metadata.(json.RawMessage); ok {
+			event.Metadata, _ = decodeMetadata(raw)
+		}
+		if shouldPurge(event) {
+			deleted[event.OrgID]++
+			continue
+		}
+		kept = append(kept, stored)
+	}
+
+	// Remaining events are compacted in insertion order
+	l.events = kept
+	l.next = 0
+	return deleted, nil
+}
+
+// Events returns every stored event across all organizations in insertion order
+func (l *MemoryAuditLogger) Events() []AuditEvent {
+	events, _ := l.filter(nil)
+	return events
+}
+
+// FindEvents returns the stored events, in insertion order, for which match returns true
+func (l *MemoryAuditLogger) FindEvents(match func(AuditEvent) bool) []AuditEvent {
+	events, _ := l.filter(match)
+	return events
+}
+
+// Len returns the number of events currently held
+func (l *MemoryAuditLogger) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.events)
+}
+
+// Dropped returns how many events were evicted because the buffer was full
+func (l *MemoryAuditLogger) Dropped() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.dropped
+}
+
+// Reset removes all stored events
+func (l *MemoryAuditLogger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = nil
+	l.next = 0
+	l.dropped = 0
+}
+
+// filter returns decoded copies of matching events in insertion order
+func (l *MemoryAuditLogger) filter(match func(AuditEvent) bool) ([]AuditEvent, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var events []AuditEvent
+	for i := 0; i < len(l.events); i++ {
+		event := l.events[(l.next+i)%len(l.events)]
+		if raw, ok := event.Metadata.(json.RawMessage); ok {
+			metadata, err := decodeMetadata(raw)
+			if err != nil {
+				return nil, err
+			}
+			event.Metadata = metadata
+		}
+		if match == nil || match(event) {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,68 @@
+// audit/memory_audit_test.go
+package audit_test
+
+import (
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestMemoryAuditLoggerRingBuffer(t *testing.T) {
+	logger := audit.NewMemoryAuditLogger(3)
+
+	for i, user := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		if err := logger.CreateAuditEvent(user, audit.ActionUserLogin, "", int64(100+i), 1, nil); err != nil {
+			t.Fatalf("Failed to create audit event: %v", err)
+		}
+	}
+
+	if logger.Len() != 3 || logger.Dropped() != 2 {
+		t.Fatalf("Expected 3 held and 2 dropped, got %d held and %d dropped", logger.Len(), logger.Dropped())
+	}
+
+	events := logger.Events()
+	for i, user := range []string{"carol", "dave", "erin"} {
+		if events[i].Username != user {
+			t.Fatalf("Expected %s at position %d, got %s", user, i, events[i].Username)
+		}
+	}
+
+	audittest.ExpectAction(t, logger, audit.ActionUserLogin, "erin")
+	audittest.ExpectNoEvent(t, logger, audittest.Match{Username: "alice"})
+
+	logger.Reset()
+	if logger.Len() != 0 {
+		t.Fatalf("Expected empty logger after reset, got %d events", logger.Len())
+	}
+}
+
+func TestMemoryLoggerFactory(t *testing.T) {
+	if err := audit.InitAuditLogger(audit.MemoryLoggerType, map[string]string{"capacity": "10"}); err != nil {
+		t.Fatalf("Failed to initialize memory logger: %v", err)
+	}
+
+	if err := audit.CreateAuditEvent("alice", audit.ActionDashboardCreate, "Created dashboard 'Ops'", 0, 42, nil); err != nil {
+		t.Fatalf("Failed to create audit event: %v", err)
+	}
+
+	logger, err := audit.GetAuditLogger()
+	if err != nil {
+		t.Fatalf("Failed to get initialized logger: %v", err)
+	}
+	memoryLogger, ok := logger.(*audit.MemoryAuditLogger)
+	if !ok {
+		t.Fatal("Expected MemoryAuditLogger, got something else")
+	}
+
+	audittest.ExpectEvent(t, memoryLogger, audittest.Match{
+		Username:         "alice",
+		ActionString:     audit.ActionDashboardCreate,
+		OrgID:            42,
+		ExtraMsgContains: "Ops",
+	})
+
+	if err := audit.InitAuditLogger(audit.MemoryLoggerType, map[string]string{"capacity": "-1"}); err == nil {
+		t.Fatal("Expected error for negative capacity")
+	}
+}
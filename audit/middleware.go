@@ -3,9 +3,9 @@ package audit
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -15,7 +15,7 @@ type ContextKey string
 const (
 	// AuditUserKey is the context key for the username
 	AuditUserKey ContextKey = "audit_username"
-	
+
 	// AuditOrgIDKey is the context key for the organization ID
 	AuditOrgIDKey ContextKey = "audit_org_id"
 )
@@ -43,86 +43,260 @@ func getOrgIDFromContext(ctx context.Context) int64 {
 	return 0
 }
 
-// AuditMiddleware creates middleware that logs HTTP requests to the audit log
-func AuditMiddleware(actionMapping map[string]string) func(http.Handler) http.Handler {
+// Request outcomes recorded by AuditMiddleware in the "outcome" metadata field
+const (
+	// OutcomeCompleted means the handler returned normally
+	OutcomeCompleted = "completed"
+	// OutcomeCanceled means the client went away before the handler returned
+	OutcomeCanceled = "canceled"
+	// OutcomeTimeout means the request context deadline passed before the handler returned
+	OutcomeTimeout = "timeout"
+	// OutcomePanic means the handler panicked
+	OutcomePanic = "panic"
+	// OutcomeAborted means the handler aborted the response with http.ErrAbortHandler
+	OutcomeAborted = "aborted"
+)
+
+// contextOutcome classifies a request by the state of its context after the handler returned
+func contextOutcome(ctx context.Context) string {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	case err != nil:
+		return OutcomeCanceled
+	}
+	return OutcomeCompleted
+}
+
+// MiddlewareOption configures AuditMiddleware
+type MiddlewareOption func(*middlewareConfig)
+
+// middlewareConfig holds the settings applied by MiddlewareOptions
+type middlewareConfig struct {
+	logger      AuditLogger
+	loggerName  string
+	redactor    *Redactor
+	rules       []RouteRule
+	bodyCapture *BodyCapture
+}
+
+// WithLogger makes the middleware write to logger instead of the package-level default.
+// The logger is also placed in the request context for use with CreateAuditEventContext.
+func WithLogger(logger AuditLogger) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.logger = logger
+	}
+}
+
+// WithNamedLogger makes the middleware write to the logger registered under name
+func WithNamedLogger(name string) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.loggerName = name
+	}
+}
+
+// WithRedactor redacts the events recorded by the middleware before they reach the logger
+func WithRedactor(redactor *Redactor) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.redactor = redactor
+	}
+}
+
+// WithRouteRules adds per-route auditing rules, e.g. loaded with LoadRouteRules.
+// A pattern may not appear both in a rule and in the action mapping.
+func WithRouteRules(rules ...RouteRule) MiddlewareOption {
+	return func(c *middlewareConfig) {
+		c.rules = append(c.rules, rules...)
+	}
+}
+
+// useLogger runs fn with the configured logger, then the request context logger, then the default
+func (c *middlewareConfig) useLogger(ctx context.Context, fn func(AuditLogger) error) error {
+	if c.logger != nil {
+		return fn(c.logger)
+	}
+	if c.loggerName != "" {
+		return useLogger(c.loggerName, fn)
+	}
+	return useContextLogger(ctx, fn)
+}
+
+// AuditMiddleware creates middleware that logs HTTP requests to the audit log.
+// The keys of actionMapping are route patterns such as "DELETE /indices/{name}";
+// the most specific matching pattern selects the action, and its path parameters
+// are recorded in the event metadata. Invalid patterns are silently skipped; use
+// NewAuditMiddleware to have them reported as an error.
+func AuditMiddleware(actionMapping map[string]string, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	config := newMiddlewareConfig(opts)
+	routes, _ := compileRoutes(actionMapping, config.rules)
+	return config.middleware(routes)
+}
+
+// NewAuditMiddleware is like AuditMiddleware but returns an error if a pattern or a
+// rule passed with WithRouteRules is invalid
+func NewAuditMiddleware(actionMapping map[string]string, opts ...MiddlewareOption) (func(http.Handler) http.Handler, error) {
+	config := newMiddlewareConfig(opts)
+	routes, err := compileRoutes(actionMapping, config.rules)
+	if err != nil {
+		return nil, fmt.Errorf("audit middleware: %v", err)
+	}
+	return config.middleware(routes), nil
+}
+
+// newMiddlewareConfig applies opts to an empty configuration
+func newMiddlewareConfig(opts []MiddlewareOption) *middlewareConfig {
+	config := &middlewareConfig{}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// middleware returns the auditing middleware for the compiled routes
+func (c *middlewareConfig) middleware(routes *routeTable) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Make an explicitly configured logger available to downstream handlers
+			if c.logger != nil {
+				r = r.WithContext(ContextWithAuditLogger(r.Context(), c.logger))
+			}
+
 			// Get username and orgID from context (set by authentication middleware)
 			username := getUsernameFromContext(r.Context())
 			orgID := getOrgIDFromContext(r.Context())
-			
+
 			// Get path and method
 			path := r.URL.Path
 			method := r.Method
-			
-			// Find the matching action based on path patterns
+
+			// Find the most specific route matching the request
 			var actionString string
-			for pattern, action := range actionMapping {
-				parts := strings.Split(pattern, " ")
-				if len(parts) != 2 {
-					continue
-				}
-				
-				methodPattern := parts[0]
-				pathPattern := parts[1]
-				
-				// Check if method matches
-				if methodPattern != "*" && methodPattern != method {
-					continue
-				}
-				
-				// Check if path matches (simple wildcard support)
-				if strings.HasSuffix(pathPattern, "*") {
-					prefix := strings.TrimSuffix(pathPattern, "*")
-					if strings.HasPrefix(path, prefix) {
-						actionString = action
-						break
-					}
-				} else if pathPattern == path {
-					actionString = action
-					break
-				}
+			var rule RouteRule
+			matched, pathParams := routes.match(method, path)
+			if matched != nil {
+				rule = matched.rule
+				actionString = rule.Action
 			}
-			
+			if !rule.audits(method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// If no specific mapping, use a generic one
 			if actionString == "" {
 				actionString = fmt.Sprintf("%s request to %s", method, path)
 			}
 
+			// Capture the request body before the handler consumes it
+			var requestBody *capturedBody
+			if c.bodyCapture != nil {
+				requestBody = captureRequestBody(r, c.bodyCapture.limit(c.bodyCapture.MaxRequestBytes))
+			}
+
 			// Create response wrapper to track status code
-			rw := &responseWriter{ResponseWriter: w, statusCode: 200}
-			
+			rw := &responseWriter{ResponseWriter: w}
+			if c.bodyCapture != nil && c.bodyCapture.Response {
+				rw.body = &bodyRecorder{limit: c.bodyCapture.limit(c.bodyCapture.MaxResponseBytes)}
+			}
+
 			// Process the request and record the time it takes
 			startTime := time.Now()
-			next.ServeHTTP(rw, r)
-			duration := time.Since(startTime)
-			
-			// Build extra message including status code and duration
-			extraMsg := fmt.Sprintf("Status: %d, Duration: %s", rw.statusCode, duration)
-			
-			// Log the audit event
-			metadata := map[string]interface{}{
-				"requestURI": r.RequestURI,
-				"userAgent": r.UserAgent(),
-				"remoteAddr": r.RemoteAddr,
-				"statusCode": rw.statusCode,
-				"durationMs": duration.Milliseconds(),
+			logEvent := func(outcome string, panicValue interface{}) {
+				duration := time.Since(startTime)
+				status := rw.status()
+				if (outcome == OutcomePanic || outcome == OutcomeAborted) && !rw.wroteHeader {
+					// No response is sent when the handler panics before writing one
+					status = 0
+				}
+				if outcome == OutcomeCompleted && !rule.records(status) {
+					return
+				}
+
+				// Build extra message including status code and duration
+				var extraMsg string
+				switch {
+				case panicValue != nil:
+					extraMsg = fmt.Sprintf("Panic: %v, Duration: %s", panicValue, duration)
+				case rw.hijacked:
+					extraMsg = fmt.Sprintf("Connection hijacked, Duration: %s", duration)
+				case outcome != OutcomeCompleted:
+					extraMsg = fmt.Sprintf("Status: %d, Outcome: %s, Duration: %s", status, outcome, duration)
+				default:
+					extraMsg = fmt.Sprintf("Status: %d, Duration: %s", status, duration)
+				}
+
+				// Log the audit event
+				metadata := map[string]interface{}{
+					"requestURI":   r.RequestURI,
+					"userAgent":    r.UserAgent(),
+					"remoteAddr":   r.RemoteAddr,
+					"durationMs":   duration.Milliseconds(),
+					"bytesWritten": rw.bytesWritten,
+					"outcome":      outcome,
+				}
+				if status != 0 {
+					metadata["statusCode"] = status
+				}
+				if panicValue != nil {
+					metadata["panic"] = fmt.Sprint(panicValue)
+				}
+				if rw.hijacked {
+					metadata["hijacked"] = true
+					if upgrade := r.Header.Get("Upgrade"); upgrade != "" {
+						metadata["upgrade"] = upgrade
+					}
+				}
+				if matched != nil {
+					metadata["route"] = matched.pattern
+				}
+				if len(pathParams) > 0 {
+					metadata["pathParams"] = pathParams
+				}
+				rule.addMetadata(metadata, r, pathParams)
+				if c.bodyCapture != nil {
+					requestBody.addMetadata(metadata, "requestBody", c.bodyCapture)
+					if rw.body != nil {
+						contentType := rw.Header().Get("Content-Type")
+						if contentType == "" {
+							contentType = http.DetectContentType(rw.body.buf.Bytes())
+						}
+						responseBody := &capturedBody{data: rw.body.buf.Bytes(), truncated: rw.body.truncated, contentType: contentType}
+						responseBody.addMetadata(metadata, "responseBody", c.bodyCapture)
+					}
+				}
+
+				// Ignore errors here - we don't want to fail the request if logging fails
+				var eventMetadata interface{} = metadata
+				if c.redactor != nil {
+					var err error
+					if extraMsg, eventMetadata, err = c.redactor.Redact(orgID, extraMsg, metadata); err != nil {
+						return
+					}
+				}
+				_ = c.useLogger(r.Context(), func(logger AuditLogger) error {
+					return logger.CreateAuditEvent(username, actionString, extraMsg, time.Now().Unix(), orgID, eventMetadata)
+				})
 			}
-			
-			// Ignore errors here - we don't want to fail the request if logging fails
-			_ = CreateAuditEvent(username, actionString, extraMsg, time.Now().Unix(), orgID, metadata)
+
+			// Record a panicking handler, then let the panic continue up the stack
+			defer func() {
+				if p := recover(); p != nil {
+					outcome := OutcomePanic
+					if p == http.ErrAbortHandler {
+						outcome = OutcomeAborted
+					}
+					logEvent(outcome, p)
+					panic(p)
+				}
+			}()
+
+			next.ServeHTTP(rw.wrap(), r)
+
+			outcome := OutcomeCompleted
+			if !rw.hijacked {
+				outcome = contextOutcome(r.Context())
+			}
+			logEvent(outcome, nil)
 		})
 	}
 }
-
-// responseWriter is a wrapper around http.ResponseWriter that captures the status code
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
-
-// WriteHeader captures the status code
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
\ No newline at end of file
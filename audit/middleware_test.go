@@ -0,0 +1,199 @@
+// audit/middleware_test.go
+package audit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestAuditMiddlewareWithLogger(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	middleware := audit.AuditMiddleware(map[string]string{
+		"POST /dashboards": audit.ActionDashboardCreate,
+	}, audit.WithLogger(logger))
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Handlers can audit through the logger carried by the request context
+		if err := audit.CreateAuditEventContext(r.Context(), "alice", audit.ActionFolderCreate, "", 0, 7, nil); err != nil {
+			t.Errorf("Failed to create audit event from handler: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/dashboards", nil)
+	req = audit.WithAuditContext(req, "alice", 7)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	audittest.ExpectEvent(t, logger, audittest.Match{Username: "alice", ActionString: audit.ActionDashboardCreate, OrgID: 7})
+	audittest.ExpectAction(t, logger, audit.ActionFolderCreate, "alice")
+}
+
+func TestContextLogger(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	ctx := audit.ContextWithAuditLogger(context.Background(), logger)
+
+	if got, ok := audit.AuditLoggerFromContext(ctx); !ok || got != logger {
+		t.Fatal("Expected logger from context")
+	}
+	if _, ok := audit.AuditLoggerFromContext(context.Background()); ok {
+		t.Fatal("Expected no logger in empty context")
+	}
+
+	if err := audit.CreateAuditEventContext(ctx, "bob", audit.ActionAlertCreate, "", 100, 3, nil); err != nil {
+		t.Fatalf("Failed to create audit event: %v", err)
+	}
+	events, err := audit.ReadAuditEventsContext(ctx, 3, 0, 0)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected 1 event from context logger, got %d (%v)", len(events), err)
+	}
+}
+
+func TestAuditMiddlewareRoutePatterns(t *testing.T) {
+	t.Parallel()
+
+	mapping := map[string]string{
+		"DELETE /indices/{name}":        audit.ActionIndexDelete,
+		"* /indices/":                   "Index request",
+		"GET /dashboards/*":             "Dashboard read",
+		"GET /dashboards/{id}":          "Dashboard viewed",
+		"GET /dashboards/shared":        "Shared dashboards listed",
+		"/files/{path...}":              "File accessed",
+		"GET /{$}":                      "Home viewed",
+		"POST /orgs/{org}/users/{user}": "Org user added",
+	}
+
+	tests := []struct {
+		method, path string
+		action       string
+		params       map[string]interface{}
+	}{
+		{"DELETE", "/indices/logs", audit.ActionIndexDelete, map[string]interface{}{"name": "logs"}},
+		{"GET", "/indices/logs", "Index request", nil},
+		{"DELETE", "/indices/logs/extra", "Index request", nil},
+		{"GET", "/dashboards/shared", "Shared dashboards listed", nil},
+		{"HEAD", "/dashboards/42", "Dashboard viewed", map[string]interface{}{"id": "42"}},
+		{"GET", "/dashboards/42/panels", "Dashboard read", nil},
+		{"PUT", "/files/a/b/c.txt", "File accessed", map[string]interface{}{"path": "a/b/c.txt"}},
+		{"GET", "/", "Home viewed", nil},
+		{"GET", "/other", "GET request to /other", nil},
+		{"POST", "/orgs/7/users/bob", "Org user added", map[string]interface{}{"org": "7", "user": "bob"}},
+	}
+
+	// Matching must not depend on map iteration order
+	for run := 0; run < 20; run++ {
+		logger := audit.NewMemoryAuditLogger(0)
+		handler := audit.AuditMiddleware(mapping, audit.WithLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+		for _, tt := range tests {
+			logger.Reset()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+
+			events := logger.Events()
+			if len(events) != 1 || events[0].ActionString != tt.action {
+				t.Fatalf("%s %s: expected action %q, got %+v", tt.method, tt.path, tt.action, events)
+			}
+			metadata := events[0].Metadata.(map[string]interface{})
+			params, _ := metadata["pathParams"].(map[string]interface{})
+			if len(params) != len(tt.params) {
+				t.Fatalf("%s %s: expected path params %v, got %v", tt.method, tt.path, tt.params, params)
+			}
+			for name, value := range tt.params {
+				if params[name] != value {
+					t.Fatalf("%s %s: expected %s=%v, got %v", tt.method, tt.path, name, value, params[name])
+				}
+			}
+		}
+	}
+}
+
+func TestAuditMiddlewareInvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	mapping := map[string]string{
+		"GET /indices/{name":  "bad",
+		"GET /indices/{name}": "Read index",
+	}
+	if _, err := audit.NewAuditMiddleware(mapping); err == nil || !strings.Contains(err.Error(), "/indices/{name") {
+		t.Fatalf("Expected NewAuditMiddleware to reject the invalid pattern, got %v", err)
+	}
+
+	// AuditMiddleware skips the invalid pattern and keeps the valid ones
+	logger := audit.NewMemoryAuditLogger(0)
+	handler := audit.AuditMiddleware(mapping, audit.WithLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/indices/logs", nil))
+
+	events := logger.Events()
+	if len(events) != 1 || events[0].ActionString != "Read index" {
+		t.Fatalf("Expected one Read index event, got %+v", events)
+	}
+}
+
+func TestAuditMiddlewarePanic(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	handler := audit.AuditMiddleware(nil, audit.WithLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Errorf("Expected the panic to be re-raised, got %v", p)
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/dashboards", nil))
+	}()
+
+	event := audittest.ExpectEvent(t, logger, audittest.Match{ExtraMsgContains: "Panic: boom"})
+	metadata := event.Metadata.(map[string]interface{})
+	if metadata["outcome"] != audit.OutcomePanic || metadata["panic"] != "boom" {
+		t.Errorf("Unexpected panic metadata: %v", metadata)
+	}
+	if _, ok := metadata["statusCode"]; ok {
+		t.Errorf("Expected no status code when nothing was written, got %v", metadata["statusCode"])
+	}
+}
+
+func TestAuditMiddlewareOutcomes(t *testing.T) {
+	t.Parallel()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithTimeout(context.Background(), 0)
+	defer cancelExpired()
+
+	tests := []struct {
+		ctx     context.Context
+		handler http.HandlerFunc
+		outcome string
+	}{
+		{context.Background(), func(w http.ResponseWriter, r *http.Request) {}, audit.OutcomeCompleted},
+		{canceled, func(w http.ResponseWriter, r *http.Request) {}, audit.OutcomeCanceled},
+		{expired, func(w http.ResponseWriter, r *http.Request) {}, audit.OutcomeTimeout},
+		{context.Background(), func(w http.ResponseWriter, r *http.Request) { panic(http.ErrAbortHandler) }, audit.OutcomeAborted},
+	}
+	for _, tt := range tests {
+		logger := audit.NewMemoryAuditLogger(0)
+		handler := audit.AuditMiddleware(nil, audit.WithLogger(logger))(tt.handler)
+		func() {
+			defer func() { recover() }()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(tt.ctx))
+		}()
+
+		metadata := audittest.ExpectEvent(t, logger, audittest.Match{}).Metadata.(map[string]interface{})
+		if metadata["outcome"] != tt.outcome {
+			t.Errorf("Expected outcome %q, got %v", tt.outcome, metadata["outcome"])
+		}
+	}
+}
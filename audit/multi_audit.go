@@ -0,0 +1,309 @@
+// audit/multi_audit.go
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SinkPolicy defines how a sink failure affects a write to a MultiAuditLogger
+type SinkPolicy int
+
+const (
+	// SinkRequired sinks fail the whole write when they fail
+	SinkRequired SinkPolicy = iota
+
+	// SinkBestEffort sinks have their failures reported but do not fail the write
+	SinkBestEffort
+)
+
+// String returns the policy name
+func (p SinkPolicy) String() string {
+	switch p {
+	case SinkRequired:
+		return "required"
+	case SinkBestEffort:
+		return "best-effort"
+	default:
+		return fmt.Sprintf("SinkPolicy(%d)", int(p))
+	}
+}
+
+// Sink is a named backend written to by a MultiAuditLogger
+type Sink struct {
+	Name   string
+	Logger AuditLogger
+	Policy SinkPolicy
+}
+
+// SinkError describes the failure of a single sink
+type SinkError struct {
+	Sink   string
+	Policy SinkPolicy
+	Err    error
+}
+
+func (e *SinkError) Error() string {
+	return fmt.Sprintf("audit sink %q (%s): %v", e.Sink, e.Policy, e.Err)
+}
+
+func (e *SinkError) Unwrap() error {
+	return e.Err
+}
+
+// MultiSinkError is returned when at least one required sink fails a write.
+// It lists every sink that failed, including best-effort ones.
+type MultiSinkError struct {
+	Errors []*SinkError
+}
+
+func (e *MultiSinkError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("failed to write audit event: %s", strings.Join(messages, "; "))
+}
+
+func (e *MultiSinkError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// SinkStatus reports write statistics for a single sink
+type SinkStatus struct {
+	Policy    SinkPolicy
+	Writes    int64
+	Failures  int64
+	LastError error
+}
+
+// MultiAuditLogger implements AuditLogger interface by writing every event to several sinks
+// and serving reads from a designated primary sink
+type MultiAuditLogger struct {
+	sinks        []Sink
+	primary      AuditLogger
+	mu           sync.Mutex
+	status       map[string]*SinkStatus
+	errorHandler func(*SinkError)
+}
+
+// NewMultiAuditLogger creates a new MultiAuditLogger; primary names the sink used for reads,
+// which must be SinkRequired
+func NewMultiAuditLogger(primary string, sinks ...Sink) (*MultiAuditLogger, error) {
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("multi logger requires at least one sink")
+	}
+
+	l := &MultiAuditLogger{
+		sinks:  sinks,
+		status: make(map[string]*SinkStatus, len(sinks)),
+	}
+	for _, sink := range sinks {
+		if sink.Name == "" {
+			return nil, fmt.Errorf("multi logger sink name must not be empty")
+		}
+		if sink.Logger == nil {
+			return nil, fmt.Errorf("multi logger sink %q has no logger", sink.Name)
+		}
+		if _, exists := l.status[sink.Name]; exists {
+			return nil, fmt.Errorf("duplicate multi logger sink name: %s", sink.Name)
+		}
+		l.status[sink.Name] = &SinkStatus{Policy: sink.Policy}
+		if sink.Name == primary {
+			// A best-effort primary could drop an event that the write reported as stored
+			if sink.Policy != SinkRequired {
+				return nil, fmt.Errorf("primary sink %q must be required, not %s", primary, sink.Policy)
+			}
+			l.primary = sink.Logger
+		}
+	}
+	if l.primary == nil {
+		return nil, fmt.Errorf("primary sink %q not found", primary)
+	}
+
+	return l, nil
+}
+
+// OnSinkError registers a handler called for every failed sink write, including best-effort sinks
+func (l *MultiAuditLogger) OnSinkError(handler func(*SinkError)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.errorHandler = handler
+}
+
+// CreateAuditEvent writes a user action to every sink. Sinks are written in order and
+// writes are not rolled back, so when a required sink fails the event may already be
+// stored in the sinks before it; callers retrying the write may duplicate it there.
+func (l *MultiAuditLogger) CreateAuditEvent(username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
+	// Resolve the timestamp once so every sink stores the same event
+	if epochTimestampSec == 0 {
+		epochTimestampSec = time.Now().Unix()
+	}
+
+	var failures []*SinkError
+	requiredFailed := false
+	for _, sink := range l.sinks {
+		err := sink.Logger.CreateAuditEvent(username, actionString, extraMsg, epochTimestampSec, orgID, metadata)
+		sinkErr := l.record(sink, err)
+		if sinkErr == nil {
+			continue
+		}
+		failures = append(failures, sinkErr)
+		if sink.Policy == SinkRequired {
+			requiredFailed = true
+		}
+	}
+
+	if requiredFailed {
+		return &MultiSinkError{Errors: failures}
+	}
+	return nil
+}
+
+// ReadAuditEvents reads audit events from the primary sink
+func (l *MultiAuditLogger) ReadAuditEvents(orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	return l.primary.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+}
+
+// Flush flushes every sink and returns the combined sink errors
+func (l *MultiAuditLogger) Flush() error {
+	return l.eachSink(AuditLogger.Flush)
+}
+
+// Close closes every sink and returns the combined sink errors
+func (l *MultiAuditLogger) Close() error {
+	return l.eachSink(AuditLogger.Close)
+}
+
+// eachSink applies fn to every sink, collecting failures as SinkErrors
+func (l *MultiAuditLogger) eachSink(fn func(AuditLogger) error) error {
+	var errs []error
+	for _, sink := range l.sinks {
+		if err := fn(sink.Logger); err != nil {
+			errs = append(errs, &SinkError{Sink: sink.Name, Policy: sink.Policy, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ScanAuditEvents scans the primary sink
+func (l *MultiAuditLogger) ScanAuditEvents(fn func(AuditEvent) error) error {
+	scanner, ok := l.primary.(AuditScanner)
+	if !ok {
+		return fmt.Errorf("primary audit sink %T does not support scanning", l.primary)
+	}
+	return scanner.ScanAuditEvents(fn)
+}
+
+// PurgeAuditEvents purges matching events from every sink that supports purging and is
+// not a protected stream. It returns the counts reported by the primary sink, or by the
+// first purged sink when the primary is protected.
+func (l *MultiAuditLogger) PurgeAuditEvents(shouldPurge func(AuditEvent) bool) (map[int64]int, error) {
+	var primaryDeleted map[int64]int
+	var errs []error
+	for _, sink := range l.sinks {
+		purger, ok := sink.Logger.(AuditPurger)
+		if !ok || isProtected(sink.Logger) {
+			continue
+		}
+		deleted, err := purger.PurgeAuditEvents(shouldPurge)
+		if err != nil {
+			errs = append(errs, &SinkError{Sink: sink.Name, Policy: sink.Policy, Err: err})
+			continue
+		}
+		if sink.Logger == l.primary || (primaryDeleted == nil && isProtected(l.primary)) {
+			primaryDeleted = deleted
+		}
+	}
+	if primaryDeleted == nil {
+		primaryDeleted = make(map[int64]int)
+	}
+
+	return primaryDeleted, errors.Join(errs...)
+}
+
+// LegalHolds returns the holds enforced by any sink
+func (l *MultiAuditLogger) LegalHolds() ([]LegalHold, error) {
+	var holds []LegalHold
+	for _, sink := range l.sinks {
+		sinkHolds, err := legalHoldsOf(sink.Logger)
+		if err != nil {
+			return nil, &SinkError{Sink: sink.Name, Policy: sink.Policy, Err: err}
+		}
+		holds = append(holds, sinkHolds...)
+	}
+	return holds, nil
+}
+
+// isProtected reports whether every sink is a protected stream; otherwise purges
+// skip the protected sinks and go ahead on the others
+func (l *MultiAuditLogger) isProtected() bool {
+	for _, sink := range l.sinks {
+		if !isProtected(sink.Logger) {
+			return false
+		}
+	}
+	return true
+}
+
+// KeyRing returns the key ring of the primary sink
+func (l *MultiAuditLogger) KeyRing() *KeyRing {
+	return keyRingOf(l.primary)
+}
+
+// scanStored scans the primary sink in its stored form
+func (l *MultiAuditLogger) scanStored(fn func(AuditEvent) error) error {
+	return scanStored(l.primary, fn)
+}
+
+// openEvent opens an event stored by the primary sink
+func (l *MultiAuditLogger) openEvent(event AuditEvent) AuditEvent {
+	return openEvent(l.primary, event)
+}
+
+// Sinks returns the configured sinks
+func (l *MultiAuditLogger) Sinks() []Sink {
+	return append([]Sink(nil), l.sinks...)
+}
+
+// SinkStatus returns a snapshot of per-sink write statistics keyed by sink name
+func (l *MultiAuditLogger) SinkStatus() map[string]SinkStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := make(map[string]SinkStatus, len(l.status))
+	for name, s := range l.status {
+		status[name] = *s
+	}
+	return status
+}
+
+// record updates the statistics for sink and reports a failure to the error handler
+func (l *MultiAuditLogger) record(sink Sink, err error) *SinkError {
+	l.mu.Lock()
+	status := l.status[sink.Name]
+	status.Writes++
+	if err != nil {
+		status.Failures++
+		status.LastError = err
+	}
+	handler := l.errorHandler
+	l.mu.Unlock()
+
+	if err == nil {
+		return nil
+	}
+
+	sinkErr := &SinkError{Sink: sink.Name, Policy: sink.Policy, Err: err}
+	if handler != nil {
+		handler(sinkErr)
+	}
+	return sinkErr
+}
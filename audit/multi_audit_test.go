@@ -0,0 +1,102 @@
+// audit/multi_audit_test.go
+package audit_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+// failingLogger is an AuditLogger whose writes always fail
+type failingLogger struct {
+	audit.AuditLogger
+}
+
+var errSinkDown = errors.New("sink down")
+
+func (failingLogger) CreateAuditEvent(username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
+	return errSinkDown
+}
+
+func TestMultiAuditLoggerConformance(t *testing.T) {
+	audittest.RunConformance(t, func(t *testing.T) audit.AuditLogger {
+		fileLogger, err := audit.NewFileAuditLogger(filepath.Join(t.TempDir(), "audit.log"))
+		if err != nil {
+			t.Fatalf("Failed to create file audit logger: %v", err)
+		}
+		logger, err := audit.NewMultiAuditLogger("memory",
+			audit.Sink{Name: "memory", Logger: audit.NewMemoryAuditLogger(0)},
+			audit.Sink{Name: "file", Logger: fileLogger},
+		)
+		if err != nil {
+			t.Fatalf("Failed to create multi audit logger: %v", err)
+		}
+		return logger
+	})
+}
+
+func TestMultiAuditLoggerSinkPolicies(t *testing.T) {
+	primary := audit.NewMemoryAuditLogger(0)
+	secondary := audit.NewMemoryAuditLogger(0)
+
+	logger, err := audit.NewMultiAuditLogger("primary",
+		audit.Sink{Name: "primary", Logger: primary, Policy: audit.SinkRequired},
+		audit.Sink{Name: "secondary", Logger: secondary, Policy: audit.SinkBestEffort},
+		audit.Sink{Name: "broken", Logger: failingLogger{}, Policy: audit.SinkBestEffort},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create multi audit logger: %v", err)
+	}
+
+	var reported []*audit.SinkError
+	logger.OnSinkError(func(err *audit.SinkError) {
+		reported = append(reported, err)
+	})
+
+	// A best-effort failure is reported but does not fail the write
+	if err := logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 0, 1, nil); err != nil {
+		t.Fatalf("Expected best-effort failure to be tolerated, got %v", err)
+	}
+	if len(reported) != 1 || reported[0].Sink != "broken" {
+		t.Fatalf("Expected one reported failure for broken sink, got %v", reported)
+	}
+	audittest.ExpectAction(t, primary, audit.ActionUserLogin, "alice")
+	audittest.ExpectAction(t, secondary, audit.ActionUserLogin, "alice")
+
+	// Both sinks must store the same resolved timestamp
+	if primary.Events()[0].EpochTimestampSec != secondary.Events()[0].EpochTimestampSec {
+		t.Fatal("Expected sinks to share the same timestamp")
+	}
+
+	status := logger.SinkStatus()
+	if status["broken"].Failures != 1 || !errors.Is(status["broken"].LastError, errSinkDown) {
+		t.Fatalf("Unexpected broken sink status: %+v", status["broken"])
+	}
+	if status["primary"].Writes != 1 || status["primary"].Failures != 0 {
+		t.Fatalf("Unexpected primary sink status: %+v", status["primary"])
+	}
+
+	// A required failure fails the write and lists every failing sink
+	strict, err := audit.NewMultiAuditLogger("primary",
+		audit.Sink{Name: "primary", Logger: primary},
+		audit.Sink{Name: "broken", Logger: failingLogger{}, Policy: audit.SinkRequired},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create multi audit logger: %v", err)
+	}
+	err = strict.CreateAuditEvent("bob", audit.ActionUserLogout, "", 0, 1, nil)
+	var multiErr *audit.MultiSinkError
+	if !errors.As(err, &multiErr) || len(multiErr.Errors) != 1 || !errors.Is(err, errSinkDown) {
+		t.Fatalf("Expected MultiSinkError wrapping sink failure, got %v", err)
+	}
+
+	if _, err := audit.NewMultiAuditLogger("missing", audit.Sink{Name: "primary", Logger: primary}); err == nil {
+		t.Fatal("Expected error for unknown primary sink")
+	}
+	if _, err := audit.NewMultiAuditLogger("primary", audit.Sink{Name: "primary", Logger: primary, Policy: audit.SinkBestEffort}); err == nil {
+		t.Fatal("Expected error for best-effort primary sink")
+	}
+}
@@ -0,0 +1,161 @@
+// audit/protected_test.go
+package audit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestAccessLogRecordsEveryRead(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	logger := audit.NewMemoryAuditLogger(0)
+	old := time.Date(2025, time.January, 10, 0, 0, 0, 0, time.UTC).Unix()
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", old, 1, nil)
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", time.Now().Unix(), 1, nil)
+
+	archiver := &audit.Archiver{Logger: logger, Dir: filepath.Join(dir, "archive"), OlderThan: 30 * 24 * time.Hour}
+	if _, err := archiver.ArchiveOnce(time.Now()); err != nil {
+		t.Fatalf("Archive run failed: %v", err)
+	}
+
+	pseudonymizer, _ := audit.NewPseudonymizer([]byte("0123456789abcdef0123456789abcdef"))
+	accessLog := audit.NewMemoryAuditLogger(0)
+	reader := &audit.AuthorizedReader{
+		Logger:        logger,
+		AccessLog:     audit.NewProtectedAuditLogger(accessLog),
+		Archiver:      archiver,
+		Pseudonymizer: pseudonymizer,
+	}
+	auditor := audit.Principal{Username: "carol", Role: audit.RoleAuditor, AssignedOrgs: []int64{1}}
+
+	if events, err := reader.ReadAuditEvents(auditor, 1, 0, 0); err != nil || len(events) != 1 {
+		t.Fatalf("Expected 1 live event, got %d (%v)", len(events), err)
+	}
+	if events, err := reader.QueryAuditEvents(auditor, 1, 0, 0, true); err != nil || len(events) != 2 {
+		t.Fatalf("Expected 2 events including the archive, got %d (%v)", len(events), err)
+	}
+	var buf bytes.Buffer
+	if err := reader.ExportAuditEvents(auditor, &buf, 1, 0, 0); err != nil || bytes.Contains(buf.Bytes(), []byte("alice")) {
+		t.Fatalf("Expected a pseudonymized export, got %q (%v)", buf.String(), err)
+	}
+	if _, err := reader.ReadAuditEvents(auditor, 2, 0, 0); !errors.Is(err, audit.ErrAccessDenied) {
+		t.Fatalf("Expected ErrAccessDenied, got %v", err)
+	}
+
+	// Access records go to the separate stream only
+	audittest.ExpectNoEvent(t, logger, audittest.Match{Username: "carol"})
+	audittest.ExpectEventCount(t, accessLog, audittest.Match{Username: "carol", ActionString: audit.ActionAuditEventsRead}, 2)
+	audittest.ExpectEventCount(t, accessLog, audittest.Match{Username: "carol", ActionString: audit.ActionAuditEventsExported}, 1)
+	audittest.ExpectEventCount(t, accessLog, audittest.Match{Username: "carol", ActionString: audit.ActionAuditEventsReadDenied, OrgID: 2}, 1)
+
+	query := audittest.ExpectEventFunc(t, accessLog, func(event audit.AuditEvent) bool {
+		metadata, _ := event.Metadata.(map[string]interface{})
+		return metadata["operation"] == "query"
+	})
+	filters := query.Metadata.(map[string]interface{})["filters"].(map[string]interface{})
+	if filters["includeArchived"] != true || query.Metadata.(map[string]interface{})["results"] != json.Number("2") {
+		t.Errorf("Unexpected query access record: %+v", query.Metadata)
+	}
+}
+
+func TestProtectedStreamCannotBePurged(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	stream := audit.NewProtectedAuditLogger(audit.NewMemoryAuditLogger(0))
+	stream.CreateAuditEvent("carol", audit.ActionAuditEventsRead, "", 1, 1, nil)
+
+	// Retention skips the stream rather than failing the job
+	job := &audit.RetentionJob{Logger: stream, Policy: audit.RetentionPolicy{Default: time.Hour}}
+	if deleted, err := job.RunOnce(time.Now()); err != nil || len(deleted) != 0 {
+		t.Fatalf("Expected retention to skip the protected stream, got %v (%v)", deleted, err)
+	}
+
+	// The archiver refuses before writing anything, even through another wrapper
+	archiveDir := filepath.Join(dir, "archive")
+	archiver := &audit.Archiver{
+		Logger:    audit.NewRedactingAuditLogger(stream, &audit.Redactor{}),
+		Dir:       archiveDir,
+		OlderThan: time.Hour,
+	}
+	if _, err := archiver.ArchiveOnce(time.Now()); !errors.Is(err, audit.ErrProtectedStream) {
+		t.Fatalf("Expected ErrProtectedStream from the archiver, got %v", err)
+	}
+	if _, err := os.Stat(archiveDir); !os.IsNotExist(err) {
+		t.Fatalf("Expected no archive to be written, got %v", err)
+	}
+
+	if _, err := stream.PurgeAuditEvents(func(audit.AuditEvent) bool { return true }); !errors.Is(err, audit.ErrProtectedStream) {
+		t.Fatalf("Expected ErrProtectedStream from a direct purge, got %v", err)
+	}
+	if events, _ := stream.ReadAuditEvents(1, 0, 0); len(events) != 1 {
+		t.Fatalf("Expected the access record to survive, got %d events", len(events))
+	}
+}
+
+func TestProtectedConfig(t *testing.T) {
+	t.Parallel()
+
+	logger, err := audit.NewAuditLoggerFromConfig(audit.Config{
+		Type:      audit.FileLoggerType,
+		File:      &audit.FileLoggerConfig{FilePath: filepath.Join(t.TempDir(), "access.log")},
+		Protected: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create logger from config: %v", err)
+	}
+	if _, ok := logger.(*audit.ProtectedAuditLogger); !ok {
+		t.Fatalf("Expected a ProtectedAuditLogger, got %T", logger)
+	}
+	logger.CreateAuditEvent("carol", audit.ActionAuditEventsRead, "", 1, 1, nil)
+	if _, err := audit.PurgeAuditEvents(logger, nil, func(audit.AuditEvent) bool { return true }); !errors.Is(err, audit.ErrProtectedStream) {
+		t.Fatalf("Expected ErrProtectedStream, got %v", err)
+	}
+}
+
+func TestProtectedSinkInMultiLogger(t *testing.T) {
+	t.Parallel()
+
+	live := audit.NewMemoryAuditLogger(0)
+	access := audit.NewProtectedAuditLogger(audit.NewMemoryAuditLogger(0))
+	logger, err := audit.NewMultiAuditLogger("live",
+		audit.Sink{Name: "live", Logger: live, Policy: audit.SinkRequired},
+		audit.Sink{Name: "access", Logger: access, Policy: audit.SinkRequired},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create multi logger: %v", err)
+	}
+	logger.CreateAuditEvent("carol", audit.ActionAuditEventsRead, "", 1, 1, nil)
+
+	// Retention purges the unprotected sink and leaves the protected one alone
+	job := &audit.RetentionJob{Logger: logger, Policy: audit.RetentionPolicy{Default: time.Hour}}
+	if deleted, err := job.RunOnce(time.Now()); err != nil || deleted[1] != 1 {
+		t.Fatalf("Expected one event purged from the live sink, got %v (%v)", deleted, err)
+	}
+	if events, _ := live.ReadAuditEvents(1, 0, 0); len(events) != 1 || events[0].ActionString != audit.ActionAuditEventsPurged {
+		t.Fatalf("Expected only the purge record in the live sink, got %+v", events)
+	}
+	if events, _ := access.ReadAuditEvents(1, 0, 0); len(events) != 2 || events[0].Username != "carol" {
+		t.Fatalf("Expected the protected sink to keep its event, got %+v", events)
+	}
+
+	// A logger made only of protected sinks is skipped as a whole
+	protected, err := audit.NewMultiAuditLogger("access", audit.Sink{Name: "access", Logger: access, Policy: audit.SinkRequired})
+	if err != nil {
+		t.Fatalf("Failed to create multi logger: %v", err)
+	}
+	archiver := &audit.Archiver{Logger: protected, Dir: filepath.Join(t.TempDir(), "archive"), OlderThan: time.Hour}
+	if _, err := archiver.ArchiveOnce(time.Now()); !errors.Is(err, audit.ErrProtectedStream) {
+		t.Fatalf("Expected ErrProtectedStream from the archiver, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+// audit/pseudonym.go
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// minPseudonymKeySize is the shortest secret accepted for keyed pseudonyms
+const minPseudonymKeySize = 16
+
+// Addresses found in free text. IPv6 matches are only candidates and are
+// replaced only if they parse, so times such as 12:30 are left alone.
+var (
+	ipv4TextPattern = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6TextPattern = regexp.MustCompile(`[0-9A-Fa-f]*:[0-9A-Fa-f:.]*[0-9A-Fa-f]`)
+)
+
+// Pseudonymizer replaces usernames and IP addresses with stable keyed pseudonyms.
+// The same value always maps to the same pseudonym under one secret, so behavior
+// per user stays analyzable, but identities cannot be recovered without the secret.
+type Pseudonymizer struct {
+	secret []byte
+
+	// IPFields are metadata paths holding IP addresses; DefaultIPFields when empty
+	IPFields []string
+
+	// UserFields are metadata paths holding usernames
+	UserFields []string
+}
+
+// NewPseudonymizer creates a Pseudonymizer keyed with secret
+func NewPseudonymizer(secret []byte) (*Pseudonymizer, error) {
+	if len(secret) < minPseudonymKeySize {
+		return nil, fmt.Errorf("pseudonym secret must be at least %d bytes", minPseudonymKeySize)
+	}
+	return &Pseudonymizer{secret: append([]byte(nil), secret...)}, nil
+}
+
+// User returns the pseudonym for a username
+func (p *Pseudonymizer) User(username string) string {
+	if username == "" {
+		return ""
+	}
+	return "user-" + p.pseudonym("user", username)
+}
+
+// IP returns the pseudonym for an IP address, ignoring any port
+func (p *Pseudonymizer) IP(addr string) string {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	}
+	if host == "" {
+		return ""
+	}
+	return "ip-" + p.pseudonym("ip", host)
+}
+
+// pseudonym computes a truncated HMAC-SHA256 of value, separated by kind so a
+// username and an IP with the same text do not share a pseudonym
+func (p *Pseudonymizer) pseudonym(kind, value string) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// Pseudonymize returns a copy of event with its username, IP fields and user fields replaced.
+// In the extra message, IP addresses and the usernames named by the event are replaced too.
+func (p *Pseudonymizer) Pseudonymize(event AuditEvent) (AuditEvent, error) {
+	metadata, err := copyMetadata(event.Metadata)
+	if err != nil {
+		return AuditEvent{}, err
+	}
+
+	usernames := []string{event.Username}
+	if object, ok := metadata.(map[string]interface{}); ok {
+		for _, path := range orDefault(p.IPFields, DefaultIPFields) {
+			updateField(object, path, p.replaceString(p.IP))
+		}
+		for _, path := range p.UserFields {
+			updateField(object, path, p.replaceString(func(username string) string {
+				usernames = append(usernames, username)
+				return p.User(username)
+			}))
+		}
+	}
+
+	event.ExtraMsg = p.pseudonymizeText(event.ExtraMsg, usernames)
+	event.Username = p.User(event.Username)
+	event.Metadata = metadata
+	return event, nil
+}
+
+// pseudonymizeText replaces whole-word occurrences of usernames and every IP address in text
+func (p *Pseudonymizer) pseudonymizeText(text string, usernames []string) string {
+	for _, username := range usernames {
+		text = replaceWord(text, username, p.User(username))
+	}
+	text = ipv4TextPattern.ReplaceAllStringFunc(text, p.IP)
+	return ipv6TextPattern.ReplaceAllStringFunc(text, func(candidate string) string {
+		if net.ParseIP(candidate) == nil {
+			return candidate
+		}
+		return p.IP(candidate)
+	})
+}
+
+// replaceWord replaces occurrences of word in text that are not part of a longer word
+func replaceWord(text, word, replacement string) string {
+	if word == "" {
+		return text
+	}
+	var out strings.Builder
+	for {
+		i := strings.Index(text, word)
+		if i < 0 {
+			out.WriteString(text)
+			return out.String()
+		}
+		end := i + len(word)
+		whole := (i == 0 || !isWordByte(text[i-1])) && (end == len(text) || !isWordByte(text[end]))
+		out.WriteString(text[:i])
+		if whole {
+			out.WriteString(replacement)
+		} else {
+			out.WriteString(word)
+		}
+		text = text[end:]
+	}
+}
+
+// isWordByte reports whether b can be part of a username
+func isWordByte(b byte) bool {
+	return b == '_' || b == '-' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// replaceString adapts fn for updateField, leaving non-string values alone
+func (p *Pseudonymizer) replaceString(fn func(string) string) func(interface{}) interface{} {
+	return func(v interface{}) interface{} {
+		if s, ok := v.(string); ok {
+			return fn(s)
+		}
+		return v
+	}
+}
+
+// ReadAuditEvents reads events from logger and returns them pseudonymized. Like the
+// logger's own ReadAuditEvents it is a privileged path that is neither authorized nor
+// recorded; serve reads on behalf of users through an AuthorizedReader.
+func (p *Pseudonymizer) ReadAuditEvents(logger AuditLogger, orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	events, err := logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, event := range events {
+		if events[i], err = p.Pseudonymize(event); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// ExportAuditEvents writes the events of orgID in the time range to w as JSON lines.
+// When pseudonymizer is non-nil, identities are replaced before the events are written.
+// It is a privileged path that is neither authorized nor recorded; export on behalf of
+// users with AuthorizedReader.ExportAuditEvents.
+func ExportAuditEvents(w io.Writer, logger AuditLogger, orgID int64, startEpochSec, endEpochSec int64, pseudonymizer *Pseudonymizer) error {
+	var events []AuditEvent
+	var err error
+	if pseudonymizer != nil {
+		events, err = pseudonymizer.ReadAuditEvents(logger, orgID, startEpochSec, endEpochSec)
+	} else {
+		events, err = logger.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+	}
+	if err != nil {
+		return err
+	}
+	return writeEvents(w, events)
+}
+
+// writeEvents writes events to w as JSON lines
+func writeEvents(w io.Writer, events []AuditEvent) error {
+	encoder := json.NewEncoder(w)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to export audit event: %v", err)
+		}
+	}
+	return nil
+}
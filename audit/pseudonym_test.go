@@ -0,0 +1,95 @@
+// audit/pseudonym_test.go
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"AuditEventsModule/audit"
+)
+
+func TestPseudonymizedExport(t *testing.T) {
+	t.Parallel()
+
+	pseudonymizer, err := audit.NewPseudonymizer([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("Failed to create pseudonymizer: %v", err)
+	}
+	pseudonymizer.UserFields = []string{"target.username"}
+
+	logger := audit.NewMemoryAuditLogger(0)
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "Login from 10.0.0.1:5000", 100, 1, map[string]interface{}{"remoteAddr": "10.0.0.1:5000"})
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 200, 1, map[string]interface{}{"remoteAddr": "10.0.0.1:6000"})
+	logger.CreateAuditEvent("bob", audit.ActionUserPasswordReset, "bob reset alice from 2001:db8::1 at 12:30", 300, 1, map[string]interface{}{
+		"remoteAddr": "10.0.0.2",
+		"target":     map[string]interface{}{"username": "alice"},
+	})
+
+	var buf bytes.Buffer
+	if err := audit.ExportAuditEvents(&buf, logger, 1, 0, 0, pseudonymizer); err != nil {
+		t.Fatalf("Failed to export: %v", err)
+	}
+	if strings.Contains(buf.String(), "alice") || strings.Contains(buf.String(), "10.0.0.") || strings.Contains(buf.String(), "2001:db8") {
+		t.Fatalf("Export contains identities: %s", buf.String())
+	}
+
+	var events []audit.AuditEvent
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event audit.AuditEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid export line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 exported events, got %d", len(events))
+	}
+
+	// Pseudonyms are stable for the same identity and distinct across identities
+	alice := pseudonymizer.User("alice")
+	ip := pseudonymizer.IP("10.0.0.1")
+	for i, event := range events[:2] {
+		if event.Username != alice || event.Metadata.(map[string]interface{})["remoteAddr"] != ip {
+			t.Errorf("Event %d: unexpected pseudonyms %+v", i, event)
+		}
+	}
+	last := events[2]
+	if last.Username == alice || last.Username != pseudonymizer.User("bob") {
+		t.Errorf("Unexpected pseudonym for bob: %q", last.Username)
+	}
+	if got := last.Metadata.(map[string]interface{})["target"].(map[string]interface{})["username"]; got != alice {
+		t.Errorf("Expected target.username %q, got %v", alice, got)
+	}
+
+	// Identities in the message map to the same pseudonyms as the fields
+	if want := "Login from " + ip + ":5000"; events[0].ExtraMsg != want {
+		t.Errorf("Expected message %q, got %q", want, events[0].ExtraMsg)
+	}
+	want := pseudonymizer.User("bob") + " reset " + alice + " from " + pseudonymizer.IP("2001:db8::1") + " at 12:30"
+	if last.ExtraMsg != want {
+		t.Errorf("Expected message %q, got %q", want, last.ExtraMsg)
+	}
+
+	// A different secret yields unrelated pseudonyms
+	other, _ := audit.NewPseudonymizer([]byte("fedcba9876543210fedcba9876543210"))
+	if other.User("alice") == alice {
+		t.Errorf("Expected pseudonyms to depend on the secret")
+	}
+
+	// The source logger still holds the original data
+	if logger.Events()[0].Username != "alice" {
+		t.Errorf("Export modified the source logger")
+	}
+}
+
+func TestNewPseudonymizerRejectsShortSecret(t *testing.T) {
+	t.Parallel()
+
+	if _, err := audit.NewPseudonymizer([]byte("short")); err == nil {
+		t.Fatal("Expected an error for a short secret")
+	}
+}
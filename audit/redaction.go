@@ -0,0 +1,324 @@
+// audit/redaction.go
+package audit
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RegexMask replaces every match of Pattern in string values with Replacement,
+// which may reference capture groups as in regexp.ReplaceAllString
+type RegexMask struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// Predefined masks for common personal data and secrets
+var (
+	EmailMask = RegexMask{
+		Name:        "email",
+		Pattern:     regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+		Replacement: "[email]",
+	}
+	BearerTokenMask = RegexMask{
+		Name:        "bearer-token",
+		Pattern:     regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9\-._~+/]+=*`),
+		Replacement: "${1}[token]",
+	}
+	JWTMask = RegexMask{
+		Name:        "jwt",
+		Pattern:     regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+		Replacement: "[token]",
+	}
+	IPv4Mask = RegexMask{
+		Name:        "ipv4",
+		Pattern:     regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`),
+		Replacement: "[ip]",
+	}
+)
+
+// Default field lists used when a RedactionPolicy leaves them empty
+var (
+	DefaultIPFields  = []string{"remoteAddr", "ipAddress"}
+	DefaultURIFields = []string{"requestURI"}
+)
+
+// RedactionPolicy describes how personal data is removed from an event before it is stored.
+// Field paths are dot-separated keys into the metadata object, e.g. "requestBody.password".
+// Steps run in order: field removal, field masking, query filtering, IP truncation, regex masks.
+type RedactionPolicy struct {
+	// RemoveFields are deleted from the metadata
+	RemoveFields []string
+
+	// MaskFields have their values replaced with RedactedValue
+	MaskFields []string
+
+	// QueryParamAllowlist, when non-nil, drops every query parameter not listed from URIFields
+	QueryParamAllowlist []string
+	URIFields           []string
+
+	// TruncateIPs reduces addresses in IPFields to their IPv4 /24 or IPv6 /48 network
+	TruncateIPs bool
+	IPFields    []string
+
+	// Masks are applied to ExtraMsg and to every string in the metadata
+	Masks []RegexMask
+}
+
+// Redactor applies a default RedactionPolicy, or an organization's own policy when one is configured
+type Redactor struct {
+	Default RedactionPolicy
+	Orgs    map[int64]RedactionPolicy
+}
+
+// PolicyFor returns the policy applied to events of orgID
+func (r *Redactor) PolicyFor(orgID int64) RedactionPolicy {
+	if policy, ok := r.Orgs[orgID]; ok {
+		return policy
+	}
+	return r.Default
+}
+
+// Redact returns redacted copies of extraMsg and metadata; the inputs are not modified
+func (r *Redactor) Redact(orgID int64, extraMsg string, metadata interface{}) (string, interface{}, error) {
+	return r.PolicyFor(orgID).Apply(extraMsg, metadata)
+}
+
+// Apply returns redacted copies of extraMsg and metadata according to the policy
+func (p RedactionPolicy) Apply(extraMsg string, metadata interface{}) (string, interface{}, error) {
+	// Work on a decoded copy so callers' maps are never modified
+	value, err := copyMetadata(metadata)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if object, ok := value.(map[string]interface{}); ok {
+		for _, path := range p.RemoveFields {
+			removeField(object, path)
+		}
+		for _, path := range p.MaskFields {
+			updateField(object, path, func(interface{}) interface{} { return RedactedValue })
+		}
+		if p.QueryParamAllowlist != nil {
+			allowed := make(map[string]bool, len(p.QueryParamAllowlist))
+			for _, param := range p.QueryParamAllowlist {
+				allowed[param] = true
+			}
+			for _, path := range orDefault(p.URIFields, DefaultURIFields) {
+				updateField(object, path, func(v interface{}) interface{} {
+					if uri, ok := v.(string); ok {
+						return filterQuery(uri, allowed)
+					}
+					return v
+				})
+			}
+		}
+		if p.TruncateIPs {
+			for _, path := range orDefault(p.IPFields, DefaultIPFields) {
+				updateField(object, path, func(v interface{}) interface{} {
+					if addr, ok := v.(string); ok {
+						return truncateIP(addr)
+					}
+					return v
+				})
+			}
+		}
+	}
+
+	if len(p.Masks) > 0 {
+		extraMsg = p.mask(extraMsg)
+		value = p.maskValue(value)
+	}
+
+	return extraMsg, value, nil
+}
+
+// copyMetadata returns a deep copy of metadata in its decoded JSON form
+func copyMetadata(metadata interface{}) (interface{}, error) {
+	data, err := encodeMetadata(metadata)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := decodeJSON(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to copy metadata: %v", err)
+	}
+	return value, nil
+}
+
+// mask applies every regex mask to s
+func (p RedactionPolicy) mask(s string) string {
+	for _, mask := range p.Masks {
+		s = mask.Pattern.ReplaceAllString(s, mask.Replacement)
+	}
+	return s
+}
+
+// maskValue applies the regex masks to every string within a decoded JSON value
+func (p RedactionPolicy) maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return p.mask(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = p.maskValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = p.maskValue(item)
+		}
+	}
+	return value
+}
+
+// removeField deletes the value at a dot-separated path
+func removeField(object map[string]interface{}, path string) {
+	parent, key, ok := walkPath(object, path)
+	if ok {
+		delete(parent, key)
+	}
+}
+
+// updateField replaces the value at a dot-separated path, if present
+func updateField(object map[string]interface{}, path string, update func(interface{}) interface{}) {
+	parent, key, ok := walkPath(object, path)
+	if !ok {
+		return
+	}
+	if value, exists := parent[key]; exists {
+		parent[key] = update(value)
+	}
+}
+
+// walkPath returns the object holding the last key of path
+func walkPath(object map[string]interface{}, path string) (map[string]interface{}, string, bool) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := object[key].(map[string]interface{})
+		if !ok {
+			return nil, "", false
+		}
+		object = next
+	}
+	return object, keys[len(keys)-1], true
+}
+
+// filterQuery drops query parameters that are not allowed from a request URI
+func filterQuery(uri string, allowed map[string]bool) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.RawQuery == "" {
+		return uri
+	}
+
+	query := parsed.Query()
+	for param := range query {
+		if !allowed[param] {
+			query.Del(param)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// truncateIP reduces an address, optionally with a port, to its IPv4 /24 or IPv6 /48 network
+func truncateIP(addr string) string {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return addr
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// orDefault returns values, or defaults when values is empty
+func orDefault(values, defaults []string) []string {
+	if len(values) == 0 {
+		return defaults
+	}
+	return values
+}
+
+// RedactingAuditLogger wraps an AuditLogger and redacts every event before it is stored
+type RedactingAuditLogger struct {
+	inner    AuditLogger
+	redactor *Redactor
+}
+
+// NewRedactingAuditLogger wraps inner so events are redacted by redactor before persistence
+func NewRedactingAuditLogger(inner AuditLogger, redactor *Redactor) *RedactingAuditLogger {
+	return &RedactingAuditLogger{
+		inner:    inner,
+		redactor: redactor,
+	}
+}
+
+// CreateAuditEvent redacts the event and logs it to the wrapped logger
+func (l *RedactingAuditLogger) CreateAuditEvent(username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
+	extraMsg, metadata, err := l.redactor.Redact(orgID, extraMsg, metadata)
+	if err != nil {
+		return err
+	}
+
+	return l.inner.CreateAuditEvent(username, actionString, extraMsg, epochTimestampSec, orgID, metadata)
+}
+
+// ReadAuditEvents reads events from the wrapped logger
+func (l *RedactingAuditLogger) ReadAuditEvents(orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	return l.inner.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+}
+
+// ScanAuditEvents scans the wrapped logger
+func (l *RedactingAuditLogger) ScanAuditEvents(fn func(AuditEvent) error) error {
+	return scanWrapped(l.inner, fn)
+}
+
+// PurgeAuditEvents purges from the wrapped logger
+func (l *RedactingAuditLogger) PurgeAuditEvents(shouldPurge func(AuditEvent) bool) (map[int64]int, error) {
+	return purgeWrapped(l.inner, shouldPurge)
+}
+
+// LegalHolds returns the holds enforced by the wrapped logger
+func (l *RedactingAuditLogger) LegalHolds() ([]LegalHold, error) {
+	return legalHoldsOf(l.inner)
+}
+
+// KeyRing returns the key ring of the wrapped logger
+func (l *RedactingAuditLogger) KeyRing() *KeyRing {
+	return keyRingOf(l.inner)
+}
+
+// isProtected reports whether the wrapped logger is a protected stream
+func (l *RedactingAuditLogger) isProtected() bool {
+	return isProtected(l.inner)
+}
+
+// scanStored scans the wrapped logger in its stored form
+func (l *RedactingAuditLogger) scanStored(fn func(AuditEvent) error) error {
+	return scanStored(l.inner, fn)
+}
+
+// openEvent opens an event stored by the wrapped logger
+func (l *RedactingAuditLogger) openEvent(event AuditEvent) AuditEvent {
+	return openEvent(l.inner, event)
+}
+
+// Flush flushes the wrapped logger
+func (l *RedactingAuditLogger) Flush() error {
+	return l.inner.Flush()
+}
+
+// Close closes the wrapped logger
+func (l *RedactingAuditLogger) Close() error {
+	return l.inner.Close()
+}
@@ -0,0 +1,124 @@
+// audit/redaction_test.go
+package audit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestRedactionPolicyApply(t *testing.T) {
+	t.Parallel()
+
+	policy := audit.RedactionPolicy{
+		RemoveFields:        []string{"userAgent", "request.password"},
+		MaskFields:          []string{"request.email"},
+		QueryParamAllowlist: []string{"page"},
+		TruncateIPs:         true,
+		Masks:               []audit.RegexMask{audit.EmailMask, audit.BearerTokenMask},
+	}
+	metadata := map[string]interface{}{
+		"userAgent":  "curl/8.0",
+		"remoteAddr": "192.168.10.77:51234",
+		"ipAddress":  "2001:db8:abcd:12::1",
+		"requestURI": "/search?q=secret&page=2&token=abc",
+		"request":    map[string]interface{}{"password": "hunter2", "email": "a@b.co", "note": "mail bob@example.com"},
+	}
+
+	extraMsg, redacted, err := policy.Apply("Authorization: Bearer abc.def", metadata)
+	if err != nil {
+		t.Fatalf("Failed to apply policy: %v", err)
+	}
+	if extraMsg != "Authorization: Bearer [token]" {
+		t.Errorf("Unexpected extraMsg: %q", extraMsg)
+	}
+
+	got := redacted.(map[string]interface{})
+	request := got["request"].(map[string]interface{})
+	checks := map[string]interface{}{
+		"remoteAddr":    got["remoteAddr"],
+		"ipAddress":     got["ipAddress"],
+		"requestURI":    got["requestURI"],
+		"request.email": request["email"],
+		"request.note":  request["note"],
+	}
+	want := map[string]interface{}{
+		"remoteAddr":    "192.168.10.0",
+		"ipAddress":     "2001:db8:abcd::",
+		"requestURI":    "/search?page=2",
+		"request.email": audit.RedactedValue,
+		"request.note":  "mail [email]",
+	}
+	for field, value := range want {
+		if checks[field] != value {
+			t.Errorf("Field %s: expected %v, got %v", field, value, checks[field])
+		}
+	}
+	if _, ok := got["userAgent"]; ok {
+		t.Errorf("Expected userAgent to be removed")
+	}
+	if _, ok := request["password"]; ok {
+		t.Errorf("Expected request.password to be removed")
+	}
+
+	// The caller's metadata is left untouched
+	if metadata["userAgent"] != "curl/8.0" || metadata["request"].(map[string]interface{})["password"] != "hunter2" {
+		t.Errorf("Input metadata was modified: %v", metadata)
+	}
+}
+
+func TestRedactorOrgOverride(t *testing.T) {
+	t.Parallel()
+
+	inner := audit.NewMemoryAuditLogger(0)
+	logger := audit.NewRedactingAuditLogger(inner, &audit.Redactor{
+		Default: audit.RedactionPolicy{MaskFields: []string{"remoteAddr"}},
+		Orgs: map[int64]audit.RedactionPolicy{
+			2: {TruncateIPs: true},
+		},
+	})
+
+	metadata := map[string]interface{}{"remoteAddr": "10.1.2.3"}
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 100, 1, metadata)
+	logger.CreateAuditEvent("bob", audit.ActionUserLogin, "", 100, 2, metadata)
+
+	for orgID, want := range map[int64]string{1: audit.RedactedValue, 2: "10.1.2.0"} {
+		events, err := logger.ReadAuditEvents(orgID, 0, 0)
+		if err != nil || len(events) != 1 {
+			t.Fatalf("Expected 1 event for org %d, got %d (%v)", orgID, len(events), err)
+		}
+		if got := events[0].Metadata.(map[string]interface{})["remoteAddr"]; got != want {
+			t.Errorf("Org %d: expected remoteAddr %q, got %v", orgID, want, got)
+		}
+	}
+}
+
+func TestAuditMiddlewareWithRedactor(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	middleware := audit.AuditMiddleware(nil, audit.WithLogger(logger), audit.WithRedactor(&audit.Redactor{
+		Default: audit.RedactionPolicy{
+			RemoveFields:        []string{"userAgent"},
+			QueryParamAllowlist: []string{},
+			TruncateIPs:         true,
+		},
+	}))
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/reset?token=secret", nil)
+	req.RemoteAddr = "203.0.113.9:4000"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	audittest.ExpectEventCount(t, logger, audittest.Match{}, 1)
+	metadata := logger.Events()[0].Metadata.(map[string]interface{})
+	if metadata["requestURI"] != "/reset" || metadata["remoteAddr"] != "203.0.113.0" {
+		t.Errorf("Unexpected redacted metadata: %v", metadata)
+	}
+	if _, ok := metadata["userAgent"]; ok {
+		t.Errorf("Expected userAgent to be removed")
+	}
+}
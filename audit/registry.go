@@ -0,0 +1,170 @@
+// audit/registry.go
+package audit
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// BackendConstructor creates an audit logger from its map configuration.
+// Constructors should reject keys they do not understand.
+type BackendConstructor func(config map[string]string) (AuditLogger, error)
+
+var (
+	backends     = make(map[LoggerType]backend)
+	backendMutex sync.RWMutex
+)
+
+// backend is a registered logger type
+type backend struct {
+	constructor BackendConstructor
+
+	// validate checks the typed Config section of a built-in backend. Backends
+	// registered with RegisterBackend have none and are configured through Options.
+	validate func(c Config, invalid func(field, format string, args ...interface{}))
+}
+
+func init() {
+	mustRegisterBackend(FileLoggerType, newFileBackend, validateFileConfig)
+	mustRegisterBackend(DBLoggerType, newDBBackend, validateDBConfig)
+	mustRegisterBackend(MemoryLoggerType, newMemoryBackend, validateMemoryConfig)
+}
+
+// RegisterBackend makes a backend available to InitAuditLogger and typed configuration under loggerType.
+// It is typically called from the init function of the package implementing the backend.
+func RegisterBackend(loggerType LoggerType, constructor BackendConstructor) error {
+	return registerBackend(loggerType, backend{constructor: constructor})
+}
+
+// mustRegisterBackend registers a built-in backend with its typed configuration, panicking on failure
+func mustRegisterBackend(loggerType LoggerType, constructor BackendConstructor, validate func(Config, func(string, string, ...interface{}))) {
+	if err := registerBackend(loggerType, backend{constructor: constructor, validate: validate}); err != nil {
+		panic(err)
+	}
+}
+
+// registerBackend adds b to the registry under loggerType
+func registerBackend(loggerType LoggerType, b backend) error {
+	if loggerType == "" {
+		return fmt.Errorf("backend logger type must not be empty")
+	}
+	if b.constructor == nil {
+		return fmt.Errorf("backend %q has no constructor", loggerType)
+	}
+
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+
+	if _, exists := backends[loggerType]; exists {
+		return fmt.Errorf("backend %q already registered", loggerType)
+	}
+	backends[loggerType] = b
+	return nil
+}
+
+// RegisteredBackends returns the registered logger types in sorted order
+func RegisteredBackends() []LoggerType {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+
+	types := make([]LoggerType, 0, len(backends))
+	for loggerType := range backends {
+		types = append(types, loggerType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// lookupBackend returns the backend registered for loggerType
+func lookupBackend(loggerType LoggerType) (backend, bool) {
+	backendMutex.RLock()
+	defer backendMutex.RUnlock()
+
+	b, ok := backends[loggerType]
+	return b, ok
+}
+
+// hasTypedConfig reports whether loggerType is registered with a typed Config section
+func hasTypedConfig(loggerType LoggerType) bool {
+	b, ok := lookupBackend(loggerType)
+	return ok && b.validate != nil
+}
+
+func validateFileConfig(c Config, invalid func(field, format string, args ...interface{})) {
+	if c.File == nil || c.File.FilePath == "" {
+		invalid("file.filePath", "file path not provided for file logger")
+	}
+	if c.File != nil {
+		c.File.validate("file", invalid)
+	}
+}
+
+func validateDBConfig(c Config, invalid func(field, format string, args ...interface{})) {
+	if c.DB == nil || c.DB.DBPath == "" {
+		invalid("db.dbPath", "database path not provided for DB logger")
+	}
+	if c.DB != nil {
+		c.DB.validate("db", invalid)
+	}
+}
+
+func validateMemoryConfig(c Config, invalid func(field, format string, args ...interface{})) {
+	if c.Memory != nil && c.Memory.Capacity < 0 {
+		invalid("memory.capacity", "must not be negative, got %d", c.Memory.Capacity)
+	}
+}
+
+func newFileBackend(config map[string]string) (AuditLogger, error) {
+	cfg, err := ConfigFromMap(FileLoggerType, config)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := cfg.File.keyRing()
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		return NewFileAuditLogger(cfg.File.FilePath)
+	}
+	logger, err := NewEncryptedFileAuditLogger(cfg.File.FilePath, keys)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.File.RequireEncryption {
+		logger.RequireEncryption()
+	}
+	return logger, nil
+}
+
+func newDBBackend(config map[string]string) (AuditLogger, error) {
+	cfg, err := ConfigFromMap(DBLoggerType, config)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := cfg.DB.keyRing()
+	if err != nil {
+		return nil, err
+	}
+	if keys == nil {
+		return NewDBAuditLogger(cfg.DB.DBPath)
+	}
+	logger, err := NewEncryptedDBAuditLogger(cfg.DB.DBPath, keys)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DB.RequireEncryption {
+		logger.RequireEncryption()
+	}
+	return logger, nil
+}
+
+func newMemoryBackend(config map[string]string) (AuditLogger, error) {
+	cfg, err := ConfigFromMap(MemoryLoggerType, config)
+	if err != nil {
+		return nil, err
+	}
+	return NewMemoryAuditLogger(cfg.Memory.Capacity), nil
+}
@@ -0,0 +1,61 @@
+// audit/registry_test.go
+package audit_test
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"AuditEventsModule/audit"
+)
+
+const boundedLoggerType audit.LoggerType = "test-bounded"
+
+func init() {
+	// A custom backend registered the way an external package would
+	audit.RegisterBackend(boundedLoggerType, func(config map[string]string) (audit.AuditLogger, error) {
+		size, err := strconv.Atoi(config["size"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid size for bounded logger: %q", config["size"])
+		}
+		return audit.NewMemoryAuditLogger(size), nil
+	})
+}
+
+func TestRegisterBackend(t *testing.T) {
+	registered := make(map[audit.LoggerType]bool)
+	for _, loggerType := range audit.RegisteredBackends() {
+		registered[loggerType] = true
+	}
+	for _, loggerType := range []audit.LoggerType{audit.FileLoggerType, audit.DBLoggerType, audit.MemoryLoggerType, boundedLoggerType} {
+		if !registered[loggerType] {
+			t.Fatalf("Expected %s in registered backends %v", loggerType, audit.RegisteredBackends())
+		}
+	}
+
+	if err := audit.RegisterBackend(audit.FileLoggerType, func(map[string]string) (audit.AuditLogger, error) { return nil, nil }); err == nil {
+		t.Fatal("Expected error when registering a duplicate backend")
+	}
+
+	logger, err := audit.NewAuditLogger(boundedLoggerType, map[string]string{"size": "2"})
+	if err != nil {
+		t.Fatalf("Failed to create custom backend: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 100, 1, nil)
+	}
+	if held := logger.(*audit.MemoryAuditLogger).Len(); held != 2 {
+		t.Fatalf("Expected custom backend to receive its options, got %d events held", held)
+	}
+
+	// Typed configuration reaches custom backends through Options
+	if _, err := audit.NewAuditLoggerFromConfig(audit.Config{Type: boundedLoggerType, Options: map[string]string{"size": "4"}}); err != nil {
+		t.Fatalf("Failed to create custom backend from config: %v", err)
+	}
+	if _, err := audit.NewAuditLoggerFromConfig(audit.Config{Type: boundedLoggerType}); err == nil {
+		t.Fatal("Expected custom backend to reject missing size")
+	}
+	if _, err := audit.NewAuditLogger("unregistered", nil); err == nil {
+		t.Fatal("Expected error for unregistered backend")
+	}
+}
@@ -0,0 +1,184 @@
+// audit/reload.go
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// ReloadAuditLogger swaps the default logger for one built from cfg
+func ReloadAuditLogger(cfg Config) error {
+	return ReloadNamedAuditLogger(DefaultLoggerName, cfg)
+}
+
+// ReloadNamedAuditLogger swaps the logger registered under name for one built from cfg.
+// The new logger is created first, so an invalid configuration leaves the current logger
+// in place. Once swapped, new convenience calls go to the new logger while calls already
+// running on the old one finish before it is flushed and closed.
+func ReloadNamedAuditLogger(name string, cfg Config) error {
+	logger, err := NewAuditLoggerFromConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to reload audit logger %q: %w", name, err)
+	}
+
+	return RegisterAuditLogger(name, logger)
+}
+
+// ConfigReloader reloads a named logger from a configuration file when the file
+// changes or the process receives one of Signals (typically syscall.SIGHUP)
+type ConfigReloader struct {
+	// Name is the logger to reload; DefaultLoggerName when empty
+	Name string
+
+	// Path is the configuration file, loaded with LoadConfig so AUDIT_* overrides still apply
+	Path string
+
+	// Interval is how often Path is checked for changes; 0 disables polling
+	Interval time.Duration
+
+	// Signals trigger an unconditional reload
+	Signals []os.Signal
+
+	// OnReload is called with the configuration after each successful reload
+	OnReload func(Config)
+
+	// OnError is called when a reload fails; the previous logger stays active
+	OnError func(error)
+
+	mu         sync.Mutex
+	loadedHash []byte // contents last loaded successfully
+	failedHash []byte // contents whose reload last failed and was reported
+}
+
+// Reload loads the configuration file and swaps the logger
+func (r *ConfigReloader) Reload() error {
+	data, err := readConfigFile(r.Path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	cfg, err := r.reloadLocked(data)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	r.reloaded(cfg)
+	return nil
+}
+
+// reloadLocked swaps the logger for one configured by data, the contents of Path,
+// and remembers them once the swap succeeds; callers must hold r.mu
+func (r *ConfigReloader) reloadLocked(data []byte) (Config, error) {
+	cfg, err := loadConfigData(r.Path, data)
+	if err != nil {
+		return cfg, err
+	}
+
+	name := r.Name
+	if name == "" {
+		name = DefaultLoggerName
+	}
+	if err := ReloadNamedAuditLogger(name, cfg); err != nil {
+		return cfg, err
+	}
+	r.loadedHash, r.failedHash = contentHash(data), nil
+	return cfg, nil
+}
+
+// Run watches for file changes and signals until ctx is done
+func (r *ConfigReloader) Run(ctx context.Context) error {
+	if r.Interval <= 0 && len(r.Signals) == 0 {
+		return fmt.Errorf("config reloader needs an interval or signals to watch")
+	}
+
+	// Remember the current contents so startup does not trigger a reload
+	if data, err := readConfigFile(r.Path); err == nil {
+		r.mu.Lock()
+		r.loadedHash = contentHash(data)
+		r.mu.Unlock()
+	}
+
+	var signals chan os.Signal
+	if len(r.Signals) > 0 {
+		signals = make(chan os.Signal, 1)
+		signal.Notify(signals, r.Signals...)
+		defer signal.Stop(signals)
+	}
+
+	var ticks <-chan time.Time
+	if r.Interval > 0 {
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-signals:
+			if err := r.Reload(); err != nil {
+				r.report(err)
+			}
+		case <-ticks:
+			r.poll()
+		}
+	}
+}
+
+// poll reloads when the file differs from the contents last loaded. A failed reload,
+// for example of a file caught mid-write, is retried on every poll until it succeeds,
+// but each distinct failing content is reported only once.
+func (r *ConfigReloader) poll() {
+	data, err := readConfigFile(r.Path)
+	if err != nil {
+		r.report(err)
+		return
+	}
+
+	r.mu.Lock()
+	hash := contentHash(data)
+	if bytes.Equal(hash, r.loadedHash) {
+		r.mu.Unlock()
+		return
+	}
+	cfg, err := r.reloadLocked(data)
+	report := err != nil && !bytes.Equal(hash, r.failedHash)
+	if err != nil {
+		r.failedHash = hash
+	}
+	r.mu.Unlock()
+
+	switch {
+	case err == nil:
+		r.reloaded(cfg)
+	case report:
+		r.report(err)
+	}
+}
+
+func (r *ConfigReloader) reloaded(cfg Config) {
+	if r.OnReload != nil {
+		r.OnReload(cfg)
+	}
+}
+
+func (r *ConfigReloader) report(err error) {
+	if r.OnError != nil {
+		r.OnError(err)
+	}
+}
+
+// contentHash returns the SHA-256 of configuration file contents
+func contentHash(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return sum[:]
+}
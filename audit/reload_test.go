@@ -0,0 +1,194 @@
+// audit/reload_test.go
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReloadDrainsInFlightEvents(t *testing.T) {
+	old := NewMemoryAuditLogger(0)
+	if err := RegisterAuditLogger("reload", old); err != nil {
+		t.Fatalf("Failed to register logger: %v", err)
+	}
+	defer UnregisterAuditLogger("reload")
+
+	// Hold a write open on the old logger while reloading
+	started := make(chan struct{})
+	release := make(chan struct{})
+	inFlight := make(chan error, 1)
+	go func() {
+		inFlight <- useLogger("reload", func(logger AuditLogger) error {
+			close(started)
+			<-release
+			return logger.CreateAuditEvent("alice", ActionUserLogin, "", 100, 1, nil)
+		})
+	}()
+	<-started
+
+	reloaded := make(chan error, 1)
+	go func() {
+		reloaded <- ReloadNamedAuditLogger("reload", Config{Type: MemoryLoggerType})
+	}()
+
+	// New writes reach the new logger while the old one is still draining
+	var current AuditLogger
+	for deadline := time.Now().Add(time.Second); ; {
+		current, _ = GetNamedAuditLogger("reload")
+		if current != AuditLogger(old) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Logger was not swapped")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := useLogger("reload", func(logger AuditLogger) error {
+		return logger.CreateAuditEvent("bob", ActionUserLogin, "", 100, 1, nil)
+	}); err != nil {
+		t.Fatalf("Failed to write to new logger: %v", err)
+	}
+
+	close(release)
+	if err := <-inFlight; err != nil {
+		t.Fatalf("In-flight write was dropped: %v", err)
+	}
+	if err := <-reloaded; err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if old.Len() != 1 || current.(*MemoryAuditLogger).Len() != 1 {
+		t.Fatalf("Expected one event in each logger, got %d old and %d new", old.Len(), current.(*MemoryAuditLogger).Len())
+	}
+	if err := old.CreateAuditEvent("carol", ActionUserLogin, "", 100, 1, nil); !errors.Is(err, ErrLoggerClosed) {
+		t.Fatalf("Expected old logger to be closed after draining, got %v", err)
+	}
+
+	// An invalid configuration keeps the current logger
+	if err := ReloadNamedAuditLogger("reload", Config{Type: FileLoggerType}); err == nil {
+		t.Fatal("Expected error for invalid configuration")
+	}
+	if logger, _ := GetNamedAuditLogger("reload"); logger != current {
+		t.Fatal("Expected current logger to survive a failed reload")
+	}
+}
+
+func TestConfigReloaderWatchesFile(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "audit.json")
+	os.WriteFile(configPath, []byte(`{"type": "memory"}`), 0644)
+
+	if err := InitNamedAuditLoggerFromConfig("watched", Config{Type: MemoryLoggerType}); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer UnregisterAuditLogger("watched")
+
+	// The file is rewritten in place, so a poll may see it half written; such a
+	// failure is retried rather than fatal
+	reloads := make(chan Config, 1)
+	reloader := &ConfigReloader{
+		Name:     "watched",
+		Path:     configPath,
+		Interval: 5 * time.Millisecond,
+		OnReload: func(cfg Config) { reloads <- cfg },
+	}
+	stop := runReloader(t, reloader)
+	defer stop()
+
+	// Give the reloader time to record the initial contents
+	time.Sleep(20 * time.Millisecond)
+	logPath := filepath.Join(dir, "audit.log")
+	os.WriteFile(configPath, []byte(`{"type": "file", "file": {"filePath": "`+logPath+`"}}`), 0644)
+
+	select {
+	case cfg := <-reloads:
+		if cfg.Type != FileLoggerType {
+			t.Fatalf("Expected file logger config, got %s", cfg.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Config change was not picked up")
+	}
+
+	logger, err := GetNamedAuditLogger("watched")
+	if err != nil {
+		t.Fatalf("Failed to get reloaded logger: %v", err)
+	}
+	if _, ok := logger.(*FileAuditLogger); !ok {
+		t.Fatalf("Expected FileAuditLogger after reload, got %T", logger)
+	}
+}
+
+func TestConfigReloaderRetriesFailedReload(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "audit.json")
+	os.WriteFile(configPath, []byte(`{"type": "memory"}`), 0644)
+
+	if err := InitNamedAuditLoggerFromConfig("retried", Config{Type: MemoryLoggerType}); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer UnregisterAuditLogger("retried")
+
+	var errorCount atomic.Int32
+	reloads := make(chan Config, 1)
+	reloader := &ConfigReloader{
+		Name:     "retried",
+		Path:     configPath,
+		Interval: 5 * time.Millisecond,
+		OnReload: func(cfg Config) { reloads <- cfg },
+		OnError:  func(error) { errorCount.Add(1) },
+	}
+	stop := runReloader(t, reloader)
+	defer stop()
+	time.Sleep(20 * time.Millisecond)
+
+	// The new configuration names a key file that does not exist yet
+	keyFile := filepath.Join(dir, "keys.json")
+	logPath := filepath.Join(dir, "audit.log")
+	os.WriteFile(configPath, []byte(`{"type": "file", "file": {"filePath": "`+logPath+`", "keyFile": "`+keyFile+`"}}`), 0644)
+	time.Sleep(50 * time.Millisecond)
+	reported := errorCount.Load()
+	if reported == 0 {
+		t.Fatal("Expected the failed reload to be reported")
+	}
+
+	// Retries of the same contents are not reported again
+	time.Sleep(50 * time.Millisecond)
+	if n := errorCount.Load(); n != reported {
+		t.Fatalf("Expected no further reports while retrying, got %d after %d", n, reported)
+	}
+
+	// Once the key file appears the unchanged configuration loads
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	os.WriteFile(keyFile, []byte(`{"activeKeyId": "k1", "keys": {"k1": "`+encoded+`"}}`), 0600)
+	select {
+	case cfg := <-reloads:
+		if cfg.Type != FileLoggerType {
+			t.Fatalf("Expected file logger config, got %s", cfg.Type)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Failed reload was not retried")
+	}
+}
+
+// runReloader runs reloader in the background and returns a function that stops it
+// and waits for it to return, so no poll outlives the test's temporary directory
+func runReloader(t *testing.T, reloader *ConfigReloader) func() {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reloader.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,167 @@
+// audit/response_writer.go
+package audit
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// responseWriter wraps the http.ResponseWriter passed to audited handlers. It records
+// the status code, the number of body bytes written and whether the connection was
+// hijacked. Handlers get it through wrap, which exposes http.Flusher, http.Hijacker and
+// io.ReaderFrom only when the underlying writer implements them. Unwrap lets
+// http.ResponseController reach the underlying writer.
+type responseWriter struct {
+	http.ResponseWriter
+	statusCode   int
+	wroteHeader  bool
+	bytesWritten int64
+	hijacked     bool
+	body         *bodyRecorder
+}
+
+// status returns the status sent to the client: the explicit status, 200 when the
+// handler wrote a body or returned without writing anything, or 0 if it hijacked the
+// connection before writing a status
+func (rw *responseWriter) status() int {
+	switch {
+	case rw.wroteHeader:
+		return rw.statusCode
+	case rw.hijacked:
+		return 0
+	}
+	return http.StatusOK
+}
+
+// WriteHeader captures the status code. Informational 1xx responses other than
+// 101 Switching Protocols may precede the final status and are not recorded.
+func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader && (code >= 200 || code == http.StatusSwitchingProtocols) {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+// Write counts body bytes, records the start of the body when response capture is
+// enabled, and notes the implicit 200 status of a write without WriteHeader
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.implicitHeader()
+	if rw.body != nil {
+		rw.body.record(p)
+	}
+	n, err := rw.ResponseWriter.Write(p)
+	rw.bytesWritten += int64(n)
+	return n, err
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// unwrapWriter is the part of responseWriter every wrapper exposes
+type unwrapWriter interface {
+	http.ResponseWriter
+	Unwrap() http.ResponseWriter
+}
+
+// wrap returns rw with exactly the optional interfaces the underlying writer has,
+// so handlers that type-assert to stream or upgrade are not misled
+func (rw *responseWriter) wrap() http.ResponseWriter {
+	_, isFlusher := rw.ResponseWriter.(http.Flusher)
+	_, isHijacker := rw.ResponseWriter.(http.Hijacker)
+	_, isReaderFrom := rw.ResponseWriter.(io.ReaderFrom)
+	f, h, r := flusher{rw}, hijacker{rw}, readerFrom{rw}
+
+	switch {
+	case isFlusher && isHijacker && isReaderFrom:
+		return struct {
+			unwrapWriter
+			http.Flusher
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, f, h, r}
+	case isFlusher && isHijacker:
+		return struct {
+			unwrapWriter
+			http.Flusher
+			http.Hijacker
+		}{rw, f, h}
+	case isFlusher && isReaderFrom:
+		return struct {
+			unwrapWriter
+			http.Flusher
+			io.ReaderFrom
+		}{rw, f, r}
+	case isHijacker && isReaderFrom:
+		return struct {
+			unwrapWriter
+			http.Hijacker
+			io.ReaderFrom
+		}{rw, h, r}
+	case isFlusher:
+		return struct {
+			unwrapWriter
+			http.Flusher
+		}{rw, f}
+	case isHijacker:
+		return struct {
+			unwrapWriter
+			http.Hijacker
+		}{rw, h}
+	case isReaderFrom:
+		return struct {
+			unwrapWriter
+			io.ReaderFrom
+		}{rw, r}
+	}
+	return struct{ unwrapWriter }{rw}
+}
+
+// flusher forwards Flush to a writer that implements http.Flusher
+type flusher struct{ rw *responseWriter }
+
+// Flush sends buffered data to the client
+func (f flusher) Flush() {
+	f.rw.implicitHeader()
+	f.rw.ResponseWriter.(http.Flusher).Flush()
+}
+
+// hijacker forwards Hijack to a writer that implements http.Hijacker
+type hijacker struct{ rw *responseWriter }
+
+// Hijack takes over the connection and records that it did
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := h.rw.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		h.rw.hijacked = true
+	}
+	return conn, buf, err
+}
+
+// readerFrom forwards ReadFrom to a writer that implements io.ReaderFrom
+type readerFrom struct{ rw *responseWriter }
+
+// ReadFrom uses the underlying writer's io.ReaderFrom, such as sendfile on a TCP
+// connection, unless the body has to be captured
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	r.rw.implicitHeader()
+	if r.rw.body == nil {
+		n, err := r.rw.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+		r.rw.bytesWritten += n
+		return n, err
+	}
+	// Hide ReadFrom so io.Copy writes through rw.Write
+	return io.Copy(struct{ io.Writer }{r.rw}, src)
+}
+
+// implicitHeader records the 200 status net/http sends on the first write
+func (rw *responseWriter) implicitHeader() {
+	if !rw.wroteHeader {
+		rw.statusCode = http.StatusOK
+		rw.wroteHeader = true
+	}
+}
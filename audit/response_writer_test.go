@@ -0,0 +1,130 @@
+// audit/response_writer_test.go
+package audit_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestAuditMiddlewareResponseWriter(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	middleware := audit.AuditMiddleware(nil, audit.WithLogger(logger))
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		status  json.Number
+		bytes   json.Number
+	}{
+		{"implicit 200", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "hello") }, "200", "5"},
+		{"no write", func(w http.ResponseWriter, r *http.Request) {}, "200", "0"},
+		{"informational then final", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusEarlyHints)
+			w.WriteHeader(http.StatusNotFound)
+		}, "404", "0"},
+		{"flush and copy", func(w http.ResponseWriter, r *http.Request) {
+			w.(http.Flusher).Flush()
+			io.Copy(w, strings.NewReader("streamed"))
+			if err := http.NewResponseController(w).Flush(); err != nil {
+				t.Errorf("ResponseController could not reach the underlying writer: %v", err)
+			}
+		}, "200", "8"},
+	}
+
+	for _, tt := range tests {
+		logger.Reset()
+		recorder := httptest.NewRecorder()
+		middleware(tt.handler).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		metadata := audittest.ExpectEvent(t, logger, audittest.Match{}).Metadata.(map[string]interface{})
+		if metadata["statusCode"] != tt.status || metadata["bytesWritten"] != tt.bytes {
+			t.Errorf("%s: expected status %s and %s bytes, got %v and %v", tt.name, tt.status, tt.bytes, metadata["statusCode"], metadata["bytesWritten"])
+		}
+		if strconv.Itoa(recorder.Body.Len()) != tt.bytes.String() {
+			t.Errorf("%s: client received %d bytes", tt.name, recorder.Body.Len())
+		}
+	}
+}
+
+func TestAuditMiddlewareHijack(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	done := make(chan struct{})
+	audited := audit.AuditMiddleware(nil, audit.WithLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Failed to hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		buf.Flush()
+	}))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		audited.ServeHTTP(w, r)
+		close(done)
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101 from the hijacked connection, got %v (%v)", resp, err)
+	}
+	<-done
+
+	event := audittest.ExpectEvent(t, logger, audittest.Match{ExtraMsgContains: "Connection hijacked"})
+	metadata := event.Metadata.(map[string]interface{})
+	if metadata["hijacked"] != true || metadata["upgrade"] != "websocket" {
+		t.Errorf("Expected a hijacked websocket upgrade, got %v", metadata)
+	}
+	if _, ok := metadata["statusCode"]; ok {
+		t.Errorf("Expected no status code for a hijacked connection, got %v", metadata["statusCode"])
+	}
+}
+
+// plainWriter is a ResponseWriter with none of the optional interfaces
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestAuditMiddlewareResponseWriterInterfaces(t *testing.T) {
+	t.Parallel()
+
+	logger := audit.NewMemoryAuditLogger(0)
+	var flushes, hijacks, readerFroms []bool
+	handler := audit.AuditMiddleware(nil, audit.WithLogger(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, isFlusher := w.(http.Flusher)
+		_, isHijacker := w.(http.Hijacker)
+		_, isReaderFrom := w.(io.ReaderFrom)
+		flushes, hijacks, readerFroms = append(flushes, isFlusher), append(hijacks, isHijacker), append(readerFroms, isReaderFrom)
+	}))
+
+	// The wrapper exposes only what the underlying writer implements
+	handler.ServeHTTP(plainWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if flushes[0] || hijacks[0] || readerFroms[0] {
+		t.Errorf("Expected no optional interfaces on a plain writer, got flusher %v, hijacker %v, reader from %v", flushes[0], hijacks[0], readerFroms[0])
+	}
+	if !flushes[1] || hijacks[1] || readerFroms[1] {
+		t.Errorf("Expected only http.Flusher on a ResponseRecorder, got flusher %v, hijacker %v, reader from %v", flushes[1], hijacks[1], readerFroms[1])
+	}
+}
@@ -0,0 +1,193 @@
+// audit/retention.go
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RetentionUsername is recorded as the user for events emitted by the retention job
+const RetentionUsername = "audit-retention"
+
+// AuditPurger is implemented by loggers that can delete stored events
+type AuditPurger interface {
+	// PurgeAuditEvents deletes every event for which shouldPurge returns true and
+	// returns the number of deleted events per organization. shouldPurge must not
+	// have side effects as it may be called more than once per event.
+	PurgeAuditEvents(shouldPurge func(AuditEvent) bool) (map[int64]int, error)
+}
+
+// purgeWrapped purges from a logger wrapped by a decorator, failing if it cannot purge
+func purgeWrapped(inner AuditLogger, shouldPurge func(AuditEvent) bool) (map[int64]int, error) {
+	purger, ok := inner.(AuditPurger)
+	if !ok {
+		return nil, fmt.Errorf("audit logger %T does not support purging", inner)
+	}
+	return purger.PurgeAuditEvents(shouldPurge)
+}
+
+// RetentionPurger is implemented by loggers that can apply a retention policy in
+// storage, without decoding every event. Events covered by holds are kept.
+type RetentionPurger interface {
+	PurgeExpiredAuditEvents(policy RetentionPolicy, now time.Time, holds []LegalHold) (map[int64]int, error)
+}
+
+// RetentionPolicy defines how long audit events are kept. A duration of 0 keeps
+// events forever. The most specific setting applies: a per-org per-action override,
+// then a per-action override, then a per-org override, then the default.
+type RetentionPolicy struct {
+	Default    time.Duration
+	Orgs       map[int64]time.Duration
+	Actions    map[string]time.Duration
+	OrgActions map[int64]map[string]time.Duration
+}
+
+// Validate checks that no retention period is negative
+func (p RetentionPolicy) Validate() error {
+	var errs []error
+	if p.Default < 0 {
+		errs = append(errs, fmt.Errorf("default retention must not be negative, got %s", p.Default))
+	}
+	for orgID, retention := range p.Orgs {
+		if retention < 0 {
+			errs = append(errs, fmt.Errorf("retention for org %d must not be negative, got %s", orgID, retention))
+		}
+	}
+	for action, retention := range p.Actions {
+		if retention < 0 {
+			errs = append(errs, fmt.Errorf("retention for action %q must not be negative, got %s", action, retention))
+		}
+	}
+	for orgID, actions := range p.OrgActions {
+		for action, retention := range actions {
+			if retention < 0 {
+				errs = append(errs, fmt.Errorf("retention for action %q in org %d must not be negative, got %s", action, orgID, retention))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RetentionFor returns how long an event with the given organization and action is kept
+func (p RetentionPolicy) RetentionFor(orgID int64, actionString string) time.Duration {
+	if retention, ok := p.OrgActions[orgID][actionString]; ok {
+		return retention
+	}
+	if retention, ok := p.Actions[actionString]; ok {
+		return retention
+	}
+	if retention, ok := p.Orgs[orgID]; ok {
+		return retention
+	}
+	return p.Default
+}
+
+// Expired reports whether event is past its retention period at now
+func (p RetentionPolicy) Expired(event AuditEvent, now time.Time) bool {
+	retention := p.RetentionFor(event.OrgID, event.ActionString)
+	if retention == 0 {
+		return false
+	}
+	return event.EpochTimestampSec < now.Add(-retention).Unix()
+}
+
+// RetentionJob periodically deletes events past their retention period, except those
+// under legal hold, and records an ActionAuditEventsPurged event in each organization it purged.
+// Protected streams are skipped.
+type RetentionJob struct {
+	// Logger must implement AuditPurger
+	Logger AuditLogger
+
+	Policy RetentionPolicy
+
+	// Holds, when set, protects events covered by an active legal hold
+	Holds *LegalHoldRegistry
+
+	// Interval between runs
+	Interval time.Duration
+
+	// OnError is called when a run fails
+	OnError func(error)
+}
+
+// RunOnce purges events expired at now and returns the number deleted per organization
+func (j *RetentionJob) RunOnce(now time.Time) (map[int64]int, error) {
+	if err := j.Policy.Validate(); err != nil {
+		return nil, err
+	}
+	if isProtected(j.Logger) {
+		return map[int64]int{}, nil
+	}
+
+	var deleted map[int64]int
+	var err error
+	if purger, ok := j.Logger.(RetentionPurger); ok {
+		var holds []LegalHold
+		if j.Holds != nil {
+			holds = j.Holds.Holds()
+		}
+		deleted, err = purger.PurgeExpiredAuditEvents(j.Policy, now, holds)
+	} else {
+		deleted, err = PurgeAuditEvents(j.Logger, j.Holds, func(event AuditEvent) bool {
+			return j.Policy.Expired(event, now)
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge expired audit events: %w", err)
+	}
+
+	// Record the purge in every affected organization, in a stable order
+	orgIDs := make([]int64, 0, len(deleted))
+	for orgID := range deleted {
+		orgIDs = append(orgIDs, orgID)
+	}
+	sort.Slice(orgIDs, func(i, k int) bool { return orgIDs[i] < orgIDs[k] })
+
+	var errs []error
+	for _, orgID := range orgIDs {
+		count := deleted[orgID]
+		if count == 0 {
+			continue
+		}
+		err := j.Logger.CreateAuditEvent(
+			RetentionUsername,
+			ActionAuditEventsPurged,
+			fmt.Sprintf("Purged %d audit events past retention", count),
+			now.Unix(),
+			orgID,
+			map[string]interface{}{
+				"deletedCount": count,
+			},
+		)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to record purge for org %d: %w", orgID, err))
+		}
+	}
+
+	return deleted, errors.Join(errs...)
+}
+
+// Run purges expired events every Interval until ctx is done
+func (j *RetentionJob) Run(ctx context.Context) error {
+	if j.Interval <= 0 {
+		return fmt.Errorf("retention job interval must be positive, got %s", j.Interval)
+	}
+
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.RunOnce(time.Now()); err != nil && j.OnError != nil {
+			j.OnError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
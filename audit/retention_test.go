@@ -0,0 +1,145 @@
+// audit/retention_test.go
+package audit_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestRetentionPolicyPrecedence(t *testing.T) {
+	policy := audit.RetentionPolicy{
+		Default: 30 * 24 * time.Hour,
+		Orgs:    map[int64]time.Duration{7: 365 * 24 * time.Hour, 8: 0},
+		Actions: map[string]time.Duration{audit.ActionUserLogin: 24 * time.Hour},
+		OrgActions: map[int64]map[string]time.Duration{
+			7: {audit.ActionIndexDelete: 7 * 365 * 24 * time.Hour},
+		},
+	}
+
+	testCases := []struct {
+		orgID    int64
+		action   string
+		expected time.Duration
+	}{
+		{1, audit.ActionDashboardCreate, 30 * 24 * time.Hour},
+		{7, audit.ActionDashboardCreate, 365 * 24 * time.Hour},
+		{8, audit.ActionDashboardCreate, 0},
+		{7, audit.ActionUserLogin, 24 * time.Hour},
+		{7, audit.ActionIndexDelete, 7 * 365 * 24 * time.Hour},
+		{1, audit.ActionIndexDelete, 30 * 24 * time.Hour},
+	}
+	for _, tc := range testCases {
+		if got := policy.RetentionFor(tc.orgID, tc.action); got != tc.expected {
+			t.Fatalf("Org %d %q: expected %s, got %s", tc.orgID, tc.action, tc.expected, got)
+		}
+	}
+
+	if err := (audit.RetentionPolicy{Default: -time.Hour}).Validate(); err == nil {
+		t.Fatal("Expected error for negative retention")
+	}
+}
+
+func TestRetentionJob(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	day := int64(24 * 60 * 60)
+	logger := audit.NewMemoryAuditLogger(0)
+
+	// Org 1 keeps 10 days, org 2 keeps forever
+	logger.CreateAuditEvent("alice", audit.ActionDashboardCreate, "old", now.Unix()-20*day, 1, nil)
+	logger.CreateAuditEvent("alice", audit.ActionDashboardCreate, "recent", now.Unix()-5*day, 1, nil)
+	logger.CreateAuditEvent("bob", audit.ActionDashboardCreate, "ancient", now.Unix()-900*day, 2, nil)
+
+	job := &audit.RetentionJob{
+		Logger: logger,
+		Policy: audit.RetentionPolicy{
+			Default: 10 * 24 * time.Hour,
+			Orgs:    map[int64]time.Duration{2: 0},
+		},
+	}
+	deleted, err := job.RunOnce(now)
+	if err != nil {
+		t.Fatalf("Retention run failed: %v", err)
+	}
+	if deleted[1] != 1 || deleted[2] != 0 {
+		t.Fatalf("Unexpected deletions: %v", deleted)
+	}
+
+	audittest.ExpectNoEvent(t, logger, audittest.Match{ExtraMsgContains: "old"})
+	audittest.ExpectEvent(t, logger, audittest.Match{ExtraMsgContains: "recent"})
+	audittest.ExpectEvent(t, logger, audittest.Match{ExtraMsgContains: "ancient"})
+	audittest.ExpectEventCount(t, logger, audittest.Match{
+		Username:     audit.RetentionUsername,
+		ActionString: audit.ActionAuditEventsPurged,
+		OrgID:        1,
+	}, 1)
+	audittest.ExpectEventCount(t, logger, audittest.Match{ActionString: audit.ActionAuditEventsPurged, OrgID: 2}, 0)
+}
+
+func TestDBRetentionMatchesPolicy(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	day := 24 * time.Hour
+	policy := audit.RetentionPolicy{
+		Default: 10 * day,
+		Orgs:    map[int64]time.Duration{2: 0, 3: 40 * day},
+		Actions: map[string]time.Duration{audit.ActionUserLogin: 2 * day, audit.ActionIndexDelete: 0},
+		OrgActions: map[int64]map[string]time.Duration{
+			3: {audit.ActionUserLogin: 0, audit.ActionDashboardCreate: 60 * day},
+			4: {audit.ActionIndexDelete: 5 * day},
+		},
+	}
+
+	dbLogger, err := audit.NewDBAuditLogger(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("Failed to create DB audit logger: %v", err)
+	}
+	defer dbLogger.Close()
+	memory := audit.NewMemoryAuditLogger(0)
+
+	// Every combination of org, action, user and age, written to both loggers
+	actions := []string{audit.ActionUserLogin, audit.ActionIndexDelete, audit.ActionDashboardCreate}
+	for orgID := int64(1); orgID <= 4; orgID++ {
+		for _, action := range actions {
+			for _, username := range []string{"alice", "bob"} {
+				for _, age := range []int{1, 3, 6, 11, 45, 70} {
+					msg := fmt.Sprintf("%s/%d", username, age)
+					ts := now.Add(-time.Duration(age) * day).Unix()
+					dbLogger.CreateAuditEvent(username, action, msg, ts, orgID, nil)
+					memory.CreateAuditEvent(username, action, msg, ts, orgID, nil)
+				}
+			}
+		}
+	}
+
+	holds, err := audit.NewLegalHoldRegistry(filepath.Join(t.TempDir(), "holds.json"), audit.NewMemoryAuditLogger(0))
+	if err != nil {
+		t.Fatalf("Failed to create legal hold registry: %v", err)
+	}
+	holds.PlaceHold(audit.LegalHold{OrgID: 1, Username: "bob", PlacedBy: "legal"})
+
+	// The DB applies the policy in SQL; the memory logger checks each event in Go
+	dbDeleted, err := (&audit.RetentionJob{Logger: dbLogger, Policy: policy, Holds: holds}).RunOnce(now)
+	if err != nil {
+		t.Fatalf("DB retention run failed: %v", err)
+	}
+	memoryDeleted, err := (&audit.RetentionJob{Logger: memory, Policy: policy, Holds: holds}).RunOnce(now)
+	if err != nil {
+		t.Fatalf("Memory retention run failed: %v", err)
+	}
+	if len(dbDeleted) != 4 || fmt.Sprint(dbDeleted) != fmt.Sprint(memoryDeleted) {
+		t.Fatalf("Expected the same deletions, got %v from the DB and %v in memory", dbDeleted, memoryDeleted)
+	}
+
+	for orgID := int64(1); orgID <= 4; orgID++ {
+		dbEvents, _ := dbLogger.ReadAuditEvents(orgID, 0, 0)
+		memoryEvents, _ := memory.ReadAuditEvents(orgID, 0, 0)
+		if len(dbEvents) != len(memoryEvents) {
+			t.Fatalf("Org %d: %d events kept in the DB, %d in memory", orgID, len(dbEvents), len(memoryEvents))
+		}
+	}
+	audittest.ExpectEvent(t, readAll{dbLogger}, audittest.Match{OrgID: 1, Username: "bob", ExtraMsgContains: "bob/70"})
+}
@@ -0,0 +1,243 @@
+// audit/routes.go
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Route patterns follow net/http.ServeMux syntax: "[METHOD ]/path", where a path
+// segment may be a literal, a wildcard "{name}" matching one segment, or a final
+// "{name...}" matching the rest of the path. A trailing "/" matches every path below
+// it and "{$}" matches only the path ending in "/". A method of "*" or no method
+// matches any method, and GET also matches HEAD. The legacy form with a trailing "*"
+// matches any path starting with the text before it.
+
+// segmentKind orders segment types from least to most specific
+type segmentKind int
+
+const (
+	segmentRest   segmentKind = iota // {name...} or a trailing "/"
+	segmentPrefix                    // legacy trailing "*"
+	segmentWildcard
+	segmentLiteral
+	segmentEnd // the pattern has no more segments
+)
+
+// routeSegment is one "/"-separated element of a route pattern
+type routeSegment struct {
+	kind  segmentKind
+	value string // literal text, or the wildcard name
+}
+
+// route is a compiled route pattern and the rule applied to requests it matches
+type route struct {
+	pattern  string
+	method   string
+	segments []routeSegment
+	rule     RouteRule
+}
+
+// routeTable matches requests against compiled routes, most specific first
+type routeTable struct {
+	routes []*route
+}
+
+// compileRoutes parses an action mapping of route patterns to action strings, plus
+// rules for finer control. A pattern may appear only once across both. Invalid and
+// duplicate patterns are left out of the table and reported together in the error.
+func compileRoutes(actionMapping map[string]string, rules []RouteRule) (*routeTable, error) {
+	all := make([]RouteRule, 0, len(actionMapping)+len(rules))
+	for pattern, action := range actionMapping {
+		all = append(all, RouteRule{Pattern: pattern, Action: action})
+	}
+	all = append(all, rules...)
+
+	table := &routeTable{}
+	seen := make(map[string]bool)
+	var errs []error
+	for _, rule := range all {
+		if seen[rule.Pattern] {
+			errs = append(errs, fmt.Errorf("duplicate route pattern %q", rule.Pattern))
+			continue
+		}
+		seen[rule.Pattern] = true
+
+		r, err := compileRule(rule)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		table.routes = append(table.routes, r)
+	}
+
+	sort.Slice(table.routes, func(i, j int) bool {
+		return table.routes[i].moreSpecific(table.routes[j])
+	})
+	return table, errors.Join(errs...)
+}
+
+// match returns the most specific route matching the request and its path parameters
+func (t *routeTable) match(method, path string) (*route, map[string]string) {
+	if t == nil {
+		return nil, nil
+	}
+	for _, r := range t.routes {
+		if params, ok := r.match(method, path); ok {
+			return r, params
+		}
+	}
+	return nil, nil
+}
+
+// parseRoute compiles a route pattern
+func parseRoute(pattern string) (*route, error) {
+	r := &route{pattern: pattern}
+
+	path := strings.TrimSpace(pattern)
+	if method, rest, ok := strings.Cut(path, " "); ok {
+		r.method, path = method, strings.TrimSpace(rest)
+		if r.method == "*" {
+			r.method = ""
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid route pattern %q: path must start with /", pattern)
+	}
+
+	parts := strings.Split(path[1:], "/")
+	names := make(map[string]bool)
+	for i, part := range parts {
+		last := i == len(parts)-1
+		switch {
+		case last && part == "":
+			// A trailing slash matches everything below it
+			r.segments = append(r.segments, routeSegment{kind: segmentRest})
+		case last && part == "{$}":
+			r.segments = append(r.segments, routeSegment{kind: segmentLiteral})
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			name := part[1 : len(part)-1]
+			kind := segmentWildcard
+			if rest, ok := strings.CutSuffix(name, "..."); ok {
+				if !last {
+					return nil, fmt.Errorf("invalid route pattern %q: {%s} must be the last segment", pattern, name)
+				}
+				name, kind = rest, segmentRest
+			}
+			if !isRouteParamName(name) {
+				return nil, fmt.Errorf("invalid route pattern %q: bad wildcard name %q", pattern, name)
+			}
+			if names[name] {
+				return nil, fmt.Errorf("invalid route pattern %q: duplicate wildcard name %q", pattern, name)
+			}
+			names[name] = true
+			r.segments = append(r.segments, routeSegment{kind: kind, value: name})
+		case last && strings.HasSuffix(part, "*"):
+			prefix := strings.TrimSuffix(part, "*")
+			if prefix == "" {
+				r.segments = append(r.segments, routeSegment{kind: segmentRest})
+			} else {
+				r.segments = append(r.segments, routeSegment{kind: segmentPrefix, value: prefix})
+			}
+		case strings.ContainsAny(part, "{}"):
+			return nil, fmt.Errorf("invalid route pattern %q: wildcard must be a whole segment", pattern)
+		default:
+			r.segments = append(r.segments, routeSegment{kind: segmentLiteral, value: part})
+		}
+	}
+	return r, nil
+}
+
+// isRouteParamName reports whether name is a valid Go identifier
+func isRouteParamName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c > 0x7f
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// match reports whether the route matches the request and returns its path parameters
+func (r *route) match(method, path string) (map[string]string, bool) {
+	if r.method != "" && r.method != method && !(r.method == "GET" && method == "HEAD") {
+		return nil, false
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+
+	parts := strings.Split(path[1:], "/")
+	var params map[string]string
+	capture := func(name, value string) {
+		if name == "" {
+			return
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[name] = value
+	}
+
+	for i, segment := range r.segments {
+		switch segment.kind {
+		case segmentRest:
+			if i >= len(parts) {
+				return nil, false
+			}
+			capture(segment.value, strings.Join(parts[i:], "/"))
+			return params, true
+		case segmentPrefix:
+			if i >= len(parts) || !strings.HasPrefix(parts[i], segment.value) {
+				return nil, false
+			}
+			return params, true
+		case segmentWildcard:
+			if i >= len(parts) || parts[i] == "" {
+				return nil, false
+			}
+			capture(segment.value, parts[i])
+		case segmentLiteral:
+			if i >= len(parts) || parts[i] != segment.value {
+				return nil, false
+			}
+		}
+	}
+	return params, len(parts) == len(r.segments)
+}
+
+// moreSpecific orders routes so that the most specific match is tried first: segments
+// are compared left to right (literal, then wildcard, then longest prefix, then rest), then
+// routes with a method come before routes for any method, then patterns sort by text
+func (r *route) moreSpecific(other *route) bool {
+	for i := 0; ; i++ {
+		a, b := r.segmentKind(i), other.segmentKind(i)
+		if a != b {
+			return a > b
+		}
+		if a == segmentEnd {
+			break
+		}
+		if a == segmentPrefix && len(r.segments[i].value) != len(other.segments[i].value) {
+			return len(r.segments[i].value) > len(other.segments[i].value)
+		}
+	}
+	if (r.method == "") != (other.method == "") {
+		return r.method != ""
+	}
+	return r.pattern < other.pattern
+}
+
+// segmentKind returns the kind of segment i, or segmentEnd past the last segment
+func (r *route) segmentKind(i int) segmentKind {
+	if i >= len(r.segments) {
+		return segmentEnd
+	}
+	return r.segments[i].kind
+}
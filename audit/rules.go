@@ -0,0 +1,144 @@
+// audit/rules.go
+package audit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// RouteRule controls how AuditMiddleware audits requests matching Pattern, a route
+// pattern in the same syntax as the keys of the action mapping
+type RouteRule struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+
+	// Action is the recorded action string; a generic one is used when empty
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+
+	// Skip disables auditing entirely, e.g. for health checks
+	Skip bool `json:"skip,omitempty" yaml:"skip,omitempty"`
+
+	// Methods, when set, limits auditing to these methods; ExcludeMethods are never audited
+	Methods        []string `json:"methods,omitempty" yaml:"methods,omitempty"`
+	ExcludeMethods []string `json:"excludeMethods,omitempty" yaml:"excludeMethods,omitempty"`
+
+	// FailuresOnly records only responses with a status of 400 or above
+	FailuresOnly bool `json:"failuresOnly,omitempty" yaml:"failuresOnly,omitempty"`
+
+	// Headers and QueryParams name request headers and query parameters to record
+	Headers     []string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	QueryParams []string `json:"queryParams,omitempty" yaml:"queryParams,omitempty"`
+
+	// TargetParam names the path parameter recorded as the event's target resource
+	TargetParam string `json:"targetParam,omitempty" yaml:"targetParam,omitempty"`
+}
+
+// RouteRulesConfig is the file format read by LoadRouteRules
+type RouteRulesConfig struct {
+	Rules []RouteRule `json:"rules" yaml:"rules"`
+}
+
+// LoadRouteRules reads and validates route rules from a .json, .yaml or .yml file.
+// Unknown fields are rejected.
+func LoadRouteRules(path string) ([]RouteRule, error) {
+	var cfg RouteRulesConfig
+	if err := decodeConfigFile(path, &cfg); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i, rule := range cfg.Rules {
+		if err := rule.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("rules[%d]: %w", i, err))
+		}
+	}
+	if _, err := compileRoutes(nil, cfg.Rules); err != nil && len(errs) == 0 {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg.Rules, nil
+}
+
+// Validate checks the rule's pattern and that TargetParam is one of its wildcards
+func (r RouteRule) Validate() error {
+	_, err := compileRule(r)
+	return err
+}
+
+// compileRule parses the rule's pattern and checks the rest of the rule against it
+func compileRule(rule RouteRule) (*route, error) {
+	r, err := parseRoute(rule.Pattern)
+	if err != nil {
+		return nil, &ConfigError{Field: "pattern", Message: err.Error()}
+	}
+
+	if rule.TargetParam != "" && !slices.ContainsFunc(r.segments, func(s routeSegment) bool {
+		return s.value == rule.TargetParam && (s.kind == segmentWildcard || s.kind == segmentRest)
+	}) {
+		return nil, &ConfigError{Field: "targetParam", Message: fmt.Sprintf("%q is not a wildcard of %q", rule.TargetParam, rule.Pattern)}
+	}
+
+	r.rule = rule
+	return r, nil
+}
+
+// audits reports whether a request with method should be audited under the rule
+func (r RouteRule) audits(method string) bool {
+	if r.Skip {
+		return false
+	}
+	if len(r.Methods) > 0 && !containsFold(r.Methods, method) {
+		return false
+	}
+	return !containsFold(r.ExcludeMethods, method)
+}
+
+// records reports whether a response with status should be recorded under the rule
+func (r RouteRule) records(status int) bool {
+	return !r.FailuresOnly || status >= http.StatusBadRequest
+}
+
+// addMetadata records the headers, query parameters and target selected by the rule
+func (r RouteRule) addMetadata(metadata map[string]interface{}, req *http.Request, pathParams map[string]string) {
+	if len(r.Headers) > 0 {
+		headers := make(map[string]string)
+		for _, name := range r.Headers {
+			if value := req.Header.Get(name); value != "" {
+				headers[http.CanonicalHeaderKey(name)] = value
+			}
+		}
+		metadata["headers"] = headers
+	}
+
+	if len(r.QueryParams) > 0 {
+		query := req.URL.Query()
+		params := make(map[string]interface{})
+		for _, name := range r.QueryParams {
+			switch values := query[name]; len(values) {
+			case 0:
+			case 1:
+				params[name] = values[0]
+			default:
+				params[name] = values
+			}
+		}
+		metadata["query"] = params
+	}
+
+	if r.TargetParam != "" {
+		if target, ok := pathParams[r.TargetParam]; ok {
+			metadata["target"] = target
+		}
+	}
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, s)
+	})
+}
@@ -0,0 +1,94 @@
+// audit/rules_test.go
+package audit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+const testRules = `rules:
+  - pattern: "GET /healthz"
+    skip: true
+  - pattern: "/dashboards/{id}"
+    action: "Dashboard changed"
+    excludeMethods: [GET, HEAD]
+    targetParam: id
+    headers: [X-Request-Id]
+    queryParams: [version]
+  - pattern: "/login"
+    action: "Login failed"
+    failuresOnly: true
+`
+
+func TestAuditMiddlewareRouteRules(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(testRules), 0644); err != nil {
+		t.Fatalf("Failed to write rules file: %v", err)
+	}
+	rules, err := audit.LoadRouteRules(path)
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+
+	logger := audit.NewMemoryAuditLogger(0)
+	middleware := audit.AuditMiddleware(nil, audit.WithLogger(logger), audit.WithRouteRules(rules...))
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" && r.FormValue("ok") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	serve := func(method, target string, header http.Header) {
+		req := httptest.NewRequest(method, target, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	serve(http.MethodGet, "/healthz", nil)
+	serve(http.MethodGet, "/dashboards/42", nil)
+	serve(http.MethodPut, "/dashboards/42?version=3&secret=x", http.Header{"X-Request-Id": {"req-1"}, "Cookie": {"session=abc"}})
+	serve(http.MethodPost, "/login?ok=1", nil)
+	serve(http.MethodPost, "/login", nil)
+
+	audittest.ExpectEventCount(t, logger, audittest.Match{}, 2)
+	audittest.ExpectEventCount(t, logger, audittest.Match{ActionString: "Login failed"}, 1)
+
+	changed := audittest.ExpectEvent(t, logger, audittest.Match{ActionString: "Dashboard changed"})
+	metadata := changed.Metadata.(map[string]interface{})
+	headers := metadata["headers"].(map[string]interface{})
+	query := metadata["query"].(map[string]interface{})
+	if metadata["target"] != "42" || headers["X-Request-Id"] != "req-1" || headers["Cookie"] != nil {
+		t.Errorf("Unexpected target or headers: %v", metadata)
+	}
+	if query["version"] != "3" || query["secret"] != nil {
+		t.Errorf("Unexpected query params: %v", query)
+	}
+}
+
+func TestRouteRuleValidation(t *testing.T) {
+	t.Parallel()
+
+	if err := (audit.RouteRule{Pattern: "DELETE /indices/{name}", TargetParam: "id"}).Validate(); err == nil {
+		t.Error("Expected an error for a target that is not a wildcard")
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(path, []byte(`{"rules": [{"pattern": "/a"}, {"pattern": "/a"}]}`), 0644)
+	if _, err := audit.LoadRouteRules(path); err == nil {
+		t.Error("Expected an error for duplicate patterns")
+	}
+
+	os.WriteFile(path, []byte(`{"rules": [{"pattern": "/a", "unknown": true}]}`), 0644)
+	if _, err := audit.LoadRouteRules(path); err == nil {
+		t.Error("Expected an error for unknown fields")
+	}
+}
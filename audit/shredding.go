@@ -0,0 +1,417 @@
+// audit/shredding.go
+package audit
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RedactedValue replaces personal fields whose subject key has been erased
+const RedactedValue = "[redacted]"
+
+// shreddedPrefix marks a value encrypted with a subject key
+const shreddedPrefix = "shred:v1:"
+
+// DefaultPersonalMetadataFields are the metadata keys encrypted by default
+var DefaultPersonalMetadataFields = []string{"remoteAddr", "userAgent", "ipAddress"}
+
+// subjectKey is the per-user key material kept by a SubjectKeyStore
+type subjectKey struct {
+	SubjectID string `json:"subjectId"`
+	Key       []byte `json:"key"`
+}
+
+// SubjectKeyStore holds one encryption key per user in a local JSON file.
+// Erasing a user destroys their key, which makes their encrypted fields unreadable.
+type SubjectKeyStore struct {
+	path      string
+	mu        sync.RWMutex
+	byUser    map[string]subjectKey
+	bySubject map[string][]byte
+}
+
+// NewSubjectKeyStore loads the key store at path, creating it on first use
+func NewSubjectKeyStore(path string) (*SubjectKeyStore, error) {
+	s := &SubjectKeyStore{
+		path:      path,
+		byUser:    make(map[string]subjectKey),
+		bySubject: make(map[string][]byte),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read subject key store: %v", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.byUser); err != nil {
+			return nil, fmt.Errorf("failed to parse subject key store: %v", err)
+		}
+		for _, key := range s.byUser {
+			s.bySubject[key.SubjectID] = key.Key
+		}
+	}
+
+	return s, nil
+}
+
+// keyForUser returns the subject ID and key for username, creating them if needed
+func (s *SubjectKeyStore) keyForUser(username string) (string, []byte, error) {
+	s.mu.RLock()
+	key, ok := s.byUser[username]
+	s.mu.RUnlock()
+	if ok {
+		return key.SubjectID, key.Key, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.byUser[username]; ok {
+		return key.SubjectID, key.Key, nil
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("failed to generate subject ID: %v", err)
+	}
+	material, err := newKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	key = subjectKey{SubjectID: hex.EncodeToString(id), Key: material}
+	s.byUser[username] = key
+	s.bySubject[key.SubjectID] = key.Key
+	if err := s.saveLocked(); err != nil {
+		delete(s.byUser, username)
+		delete(s.bySubject, key.SubjectID)
+		return "", nil, err
+	}
+
+	return key.SubjectID, key.Key, nil
+}
+
+// keyForSubject returns the key for a subject ID, or false if it was erased or never existed
+func (s *SubjectKeyStore) keyForSubject(subjectID string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.bySubject[subjectID]
+	return key, ok
+}
+
+// EraseSubject destroys the key for username and returns its subject ID
+func (s *SubjectKeyStore) EraseSubject(username string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.byUser[username]
+	if !ok {
+		return "", fmt.Errorf("no key held for user %q", username)
+	}
+
+	delete(s.byUser, username)
+	delete(s.bySubject, key.SubjectID)
+	if err := s.saveLocked(); err != nil {
+		s.byUser[username] = key
+		s.bySubject[key.SubjectID] = key.Key
+		return "", err
+	}
+
+	return key.SubjectID, nil
+}
+
+// saveLocked atomically writes the key store; callers must hold s.mu
+func (s *SubjectKeyStore) saveLocked() error {
+	data, err := json.Marshal(s.byUser)
+	if err != nil {
+		return fmt.Errorf("failed to marshal subject key store: %v", err)
+	}
+	return writeFileAtomic(s.path, data, 0600)
+}
+
+// ShreddingAuditLogger wraps an AuditLogger and encrypts personal fields with a
+// per-user key before they are stored. Erasing a user destroys the key, so their
+// Username, extra message and personal metadata read back as RedactedValue while
+// the stored records, and anything computed over them, stay unchanged.
+type ShreddingAuditLogger struct {
+	inner          AuditLogger
+	keys           *SubjectKeyStore
+	personalFields map[string]bool
+}
+
+// NewShreddingAuditLogger wraps inner; personalFields lists the top-level metadata keys
+// to encrypt and defaults to DefaultPersonalMetadataFields
+func NewShreddingAuditLogger(inner AuditLogger, keys *SubjectKeyStore, personalFields ...string) *ShreddingAuditLogger {
+	if len(personalFields) == 0 {
+		personalFields = DefaultPersonalMetadataFields
+	}
+	fields := make(map[string]bool, len(personalFields))
+	for _, field := range personalFields {
+		fields[field] = true
+	}
+
+	return &ShreddingAuditLogger{
+		inner:          inner,
+		keys:           keys,
+		personalFields: fields,
+	}
+}
+
+// CreateAuditEvent encrypts personal fields and logs the event to the wrapped logger
+func (l *ShreddingAuditLogger) CreateAuditEvent(username, actionString, extraMsg string, epochTimestampSec, orgID int64, metadata interface{}) error {
+	if username == "" {
+		return l.inner.CreateAuditEvent(username, actionString, extraMsg, epochTimestampSec, orgID, metadata)
+	}
+
+	subjectID, key, err := l.keys.keyForUser(username)
+	if err != nil {
+		return err
+	}
+
+	encryptedUsername, err := shredValue(subjectID, key, "username", username)
+	if err != nil {
+		return err
+	}
+
+	// Messages are free text and often name addresses or other users
+	if extraMsg != "" {
+		if extraMsg, err = shredValue(subjectID, key, "extraMsg", extraMsg); err != nil {
+			return err
+		}
+	}
+
+	metadataMap, err := metadataAsMap(metadata)
+	if err != nil {
+		return err
+	}
+	if metadataMap != nil {
+		for field := range l.personalFields {
+			value, ok := metadataMap[field]
+			if !ok || value == nil {
+				continue
+			}
+			if metadataMap[field], err = shredValue(subjectID, key, field, value); err != nil {
+				return err
+			}
+		}
+		metadata = metadataMap
+	}
+
+	return l.inner.CreateAuditEvent(encryptedUsername, actionString, extraMsg, epochTimestampSec, orgID, metadata)
+}
+
+// ReadAuditEvents reads events from the wrapped logger and decrypts personal fields
+func (l *ShreddingAuditLogger) ReadAuditEvents(orgID int64, startEpochSec, endEpochSec int64) ([]AuditEvent, error) {
+	events, err := l.inner.ReadAuditEvents(orgID, startEpochSec, endEpochSec)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range events {
+		events[i] = l.decryptEvent(events[i])
+	}
+	return events, nil
+}
+
+// ScanAuditEvents scans the wrapped logger, passing decrypted events to fn
+func (l *ShreddingAuditLogger) ScanAuditEvents(fn func(AuditEvent) error) error {
+	return scanWrapped(l.inner, func(event AuditEvent) error {
+		return fn(l.decryptEvent(event))
+	})
+}
+
+// PurgeAuditEvents purges from the wrapped logger, matching against decrypted events
+// so legal holds and retention rules keyed on usernames still apply. The wrapped
+// logger only sees encrypted usernames, so its holds are checked here.
+func (l *ShreddingAuditLogger) PurgeAuditEvents(shouldPurge func(AuditEvent) bool) (map[int64]int, error) {
+	holds, err := l.LegalHolds()
+	if err != nil {
+		return nil, err
+	}
+	return purgeWrapped(l.inner, func(event AuditEvent) bool {
+		event = l.decryptEvent(event)
+		return !holdsCover(holds, event) && shouldPurge(event)
+	})
+}
+
+// LegalHolds returns the holds enforced by the wrapped logger
+func (l *ShreddingAuditLogger) LegalHolds() ([]LegalHold, error) {
+	return legalHoldsOf(l.inner)
+}
+
+// KeyRing returns the key ring of the wrapped logger
+func (l *ShreddingAuditLogger) KeyRing() *KeyRing {
+	return keyRingOf(l.inner)
+}
+
+// isProtected reports whether the wrapped logger is a protected stream
+func (l *ShreddingAuditLogger) isProtected() bool {
+	return isProtected(l.inner)
+}
+
+// scanStored scans the wrapped logger without decrypting, so archives keep
+// personal fields under the subject keys and erasure still applies to them
+func (l *ShreddingAuditLogger) scanStored(fn func(AuditEvent) error) error {
+	return scanStored(l.inner, fn)
+}
+
+// openEvent decrypts an event stored by the wrapped logger
+func (l *ShreddingAuditLogger) openEvent(event AuditEvent) AuditEvent {
+	return l.decryptEvent(openEvent(l.inner, event))
+}
+
+// Flush flushes the wrapped logger
+func (l *ShreddingAuditLogger) Flush() error {
+	return l.inner.Flush()
+}
+
+// Close closes the wrapped logger
+func (l *ShreddingAuditLogger) Close() error {
+	return l.inner.Close()
+}
+
+// EraseUser destroys the key for username and records the erasure, attributed to erasedBy,
+// using the user's subject ID rather than their name. The erasure is recorded in every
+// organization the user has events in, or in organization 0 if there are none. If a legal
+// hold covers any of the user's events, it fails with ErrLegalHold and keeps the key.
+func (l *ShreddingAuditLogger) EraseUser(username, erasedBy string) error {
+	holds, err := l.LegalHolds()
+	if err != nil {
+		return err
+	}
+	if holdsCover(holds, AuditEvent{Username: username}) {
+		return fmt.Errorf("%w: user %q", ErrLegalHold, username)
+	}
+
+	orgs := make(map[int64]bool)
+	err = scanWrapped(l.inner, func(event AuditEvent) error {
+		if value, ok := l.unshred("username", event.Username); !ok || value != username {
+			return nil
+		}
+		if holdsCover(holds, AuditEvent{Username: username, OrgID: event.OrgID}) {
+			return fmt.Errorf("%w: user %q in organization %d", ErrLegalHold, username, event.OrgID)
+		}
+		orgs[event.OrgID] = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(orgs) == 0 {
+		orgs[0] = true
+	}
+
+	subjectID, err := l.keys.EraseSubject(username)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, orgID := range sortedOrgIDs(orgs) {
+		err := l.inner.CreateAuditEvent(
+			erasedBy,
+			ActionUserDataErased,
+			fmt.Sprintf("Erased personal data for subject %s", subjectID),
+			now,
+			orgID,
+			map[string]interface{}{
+				"subjectId": subjectID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptEvent decrypts the personal fields of event, redacting those whose key was erased
+func (l *ShreddingAuditLogger) decryptEvent(event AuditEvent) AuditEvent {
+	if value, ok := l.unshred("username", event.Username); ok {
+		event.Username, _ = value.(string)
+	}
+	if value, ok := l.unshred("extraMsg", event.ExtraMsg); ok {
+		event.ExtraMsg, _ = value.(string)
+	}
+
+	metadataMap, ok := event.Metadata.(map[string]interface{})
+	if !ok {
+		return event
+	}
+	decrypted := make(map[string]interface{}, len(metadataMap))
+	for field, value := range metadataMap {
+		if text, isString := value.(string); isString && l.personalFields[field] {
+			if plain, ok := l.unshred(field, text); ok {
+				value = plain
+			}
+		}
+		decrypted[field] = value
+	}
+	event.Metadata = decrypted
+	return event
+}
+
+// unshred decrypts a shredded value; ok is false if value was not shredded
+func (l *ShreddingAuditLogger) unshred(field, value string) (interface{}, bool) {
+	rest, ok := strings.CutPrefix(value, shreddedPrefix)
+	if !ok {
+		return nil, false
+	}
+
+	subjectID, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return RedactedValue, true
+	}
+	key, ok := l.keys.keyForSubject(subjectID)
+	if !ok {
+		return RedactedValue, true
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return RedactedValue, true
+	}
+	plaintext, err := open(key, ciphertext, []byte(subjectID+"|"+field))
+	if err != nil {
+		return RedactedValue, true
+	}
+
+	var decoded interface{}
+	if err := decodeJSON(plaintext, &decoded); err != nil {
+		return RedactedValue, true
+	}
+	return decoded, true
+}
+
+// shredValue encrypts the JSON encoding of value with a subject key, bound to field
+func shredValue(subjectID string, key []byte, field string, value interface{}) (string, error) {
+	plaintext, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal personal field %s: %v", field, err)
+	}
+	ciphertext, err := seal(key, plaintext, []byte(subjectID+"|"+field))
+	if err != nil {
+		return "", err
+	}
+	return shreddedPrefix + subjectID + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// metadataAsMap returns a copy of metadata as a map, or nil if it is not a JSON object
+func metadataAsMap(metadata interface{}) (map[string]interface{}, error) {
+	data, err := encodeMetadata(metadata)
+	if err != nil || data == nil {
+		return nil, err
+	}
+	var metadataMap map[string]interface{}
+	if err := decodeJSON(data, &metadataMap); err != nil {
+		return nil, nil
+	}
+	return metadataMap, nil
+}
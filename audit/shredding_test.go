@@ -0,0 +1,174 @@
+// audit/shredding_test.go
+package audit_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"AuditEventsModule/audit"
+	"AuditEventsModule/audit/audittest"
+)
+
+func TestShreddingAuditLoggerConformance(t *testing.T) {
+	audittest.RunConformance(t, func(t *testing.T) audit.AuditLogger {
+		keys, err := audit.NewSubjectKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+		if err != nil {
+			t.Fatalf("Failed to create key store: %v", err)
+		}
+		return audit.NewShreddingAuditLogger(audit.NewMemoryAuditLogger(0), keys)
+	})
+}
+
+func TestShreddingEraseUser(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "keys.json")
+	keys, err := audit.NewSubjectKeyStore(keyPath)
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+
+	inner := audit.NewMemoryAuditLogger(0)
+	logger := audit.NewShreddingAuditLogger(inner, keys)
+
+	metadata := map[string]interface{}{"remoteAddr": "10.1.2.3:5555", "dashboardId": "dash-1"}
+	logger.CreateAuditEvent("alice", audit.ActionDashboardCreate, "Login from 10.1.2.3", 100, 1, metadata)
+	logger.CreateAuditEvent("bob", audit.ActionDashboardCreate, "Login from 10.1.2.3", 101, 1, metadata)
+
+	// Stored records hold ciphertext only
+	for _, event := range inner.Events() {
+		stored := event.Metadata.(map[string]interface{})
+		if strings.Contains(event.Username, "alice") || strings.Contains(stored["remoteAddr"].(string), "10.1.2.3") ||
+			strings.Contains(event.ExtraMsg, "10.1.2.3") {
+			t.Fatalf("Personal data stored in clear: %+v", event)
+		}
+	}
+	before := inner.Events()
+
+	// Erasure survives reopening the key store
+	if err := logger.EraseUser("alice", "dpo"); err != nil {
+		t.Fatalf("Failed to erase user: %v", err)
+	}
+	keys, err = audit.NewSubjectKeyStore(keyPath)
+	if err != nil {
+		t.Fatalf("Failed to reopen key store: %v", err)
+	}
+	logger = audit.NewShreddingAuditLogger(inner, keys)
+
+	// The erasure is recorded in the organization holding the user's events
+	events, err := logger.ReadAuditEvents(1, 0, 0)
+	if err != nil || len(events) != 3 {
+		t.Fatalf("Expected 2 events and the erasure, got %d (%v)", len(events), err)
+	}
+	if events[2].ActionString != audit.ActionUserDataErased || events[2].Username != "dpo" {
+		t.Fatalf("Expected the erasure by dpo in org 1, got %+v", events[2])
+	}
+
+	alice := events[0].Metadata.(map[string]interface{})
+	if events[0].Username != audit.RedactedValue || alice["remoteAddr"] != audit.RedactedValue || events[0].ExtraMsg != audit.RedactedValue {
+		t.Fatalf("Expected erased user to be redacted, got %+v", events[0])
+	}
+	if alice["dashboardId"] != "dash-1" || events[0].ActionString != audit.ActionDashboardCreate {
+		t.Fatalf("Expected non-personal fields intact, got %+v", events[0])
+	}
+	bob := events[1].Metadata.(map[string]interface{})
+	if events[1].Username != "bob" || bob["remoteAddr"] != "10.1.2.3:5555" || events[1].ExtraMsg != "Login from 10.1.2.3" {
+		t.Fatalf("Expected other users readable, got %+v", events[1])
+	}
+
+	// The stored records themselves are untouched by erasure
+	after := inner.Events()
+	if after[0].Username != before[0].Username {
+		t.Fatal("Expected stored ciphertext to stay unchanged")
+	}
+	audittest.ExpectAction(t, inner, audit.ActionUserDataErased, "dpo")
+}
+
+func TestShreddingEraseUserLegalHold(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.log")
+	inner, err := audit.NewFileAuditLogger(logPath)
+	if err != nil {
+		t.Fatalf("Failed to create file audit logger: %v", err)
+	}
+	keys, err := audit.NewSubjectKeyStore(filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+	logger := audit.NewShreddingAuditLogger(inner, keys)
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "", 100, 2, nil)
+
+	holds, err := audit.NewLegalHoldRegistry(audit.LegalHoldPath(logPath), audit.NewMemoryAuditLogger(0))
+	if err != nil {
+		t.Fatalf("Failed to create legal hold registry: %v", err)
+	}
+	hold, err := holds.PlaceHold(audit.LegalHold{OrgID: 2, Reason: "Case 7", PlacedBy: "counsel"})
+	if err != nil {
+		t.Fatalf("Failed to place hold: %v", err)
+	}
+
+	if err := logger.EraseUser("alice", "dpo"); !errors.Is(err, audit.ErrLegalHold) {
+		t.Fatalf("Expected ErrLegalHold, got %v", err)
+	}
+	events, _ := logger.ReadAuditEvents(2, 0, 0)
+	if len(events) != 1 || events[0].Username != "alice" {
+		t.Fatalf("Expected held user data to stay readable, got %+v", events)
+	}
+
+	if err := holds.ReleaseHold(hold.ID, "counsel"); err != nil {
+		t.Fatalf("Failed to release hold: %v", err)
+	}
+	if err := logger.EraseUser("alice", "dpo"); err != nil {
+		t.Fatalf("Failed to erase user after release: %v", err)
+	}
+	events, _ = logger.ReadAuditEvents(2, 0, 0)
+	if len(events) != 2 || events[1].ActionString != audit.ActionUserDataErased {
+		t.Fatalf("Expected the erasure in org 2, got %+v", events)
+	}
+}
+
+func TestShreddingArchive(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := audit.NewSubjectKeyStore(filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatalf("Failed to create key store: %v", err)
+	}
+	logger := audit.NewShreddingAuditLogger(audit.NewMemoryAuditLogger(0), keys)
+
+	now := time.Date(2025, time.June, 15, 0, 0, 0, 0, time.UTC)
+	jan := time.Date(2025, time.January, 10, 0, 0, 0, 0, time.UTC).Unix()
+	logger.CreateAuditEvent("alice", audit.ActionUserLogin, "Login from 10.1.2.3", jan, 1, map[string]interface{}{"remoteAddr": "10.1.2.3"})
+
+	archiver := &audit.Archiver{Logger: logger, Dir: filepath.Join(dir, "archive"), OlderThan: 30 * 24 * time.Hour}
+	if archived, err := archiver.ArchiveOnce(now); err != nil || archived[1] != 1 {
+		t.Fatalf("Expected 1 archived event, got %v (%v)", archived, err)
+	}
+
+	// Archives keep the subject-key ciphertext
+	raw, err := audit.ReadArchivedAuditEvents(archiver.Dir, 1, 0, 0)
+	if err != nil || len(raw) != 1 {
+		t.Fatalf("Expected 1 archived event, got %+v (%v)", raw, err)
+	}
+	if strings.Contains(raw[0].Username, "alice") || strings.Contains(raw[0].ExtraMsg, "10.1.2.3") {
+		t.Fatalf("Personal data archived in clear: %+v", raw[0])
+	}
+	events, err := archiver.ReadAuditEvents(1, 0, 0, true)
+	if err != nil || len(events) != 1 || events[0].Username != "alice" {
+		t.Fatalf("Expected the archived event to read back decrypted, got %+v (%v)", events, err)
+	}
+
+	// Erasure reaches archived events too
+	if err := logger.EraseUser("alice", "dpo"); err != nil {
+		t.Fatalf("Failed to erase user: %v", err)
+	}
+	events, _ = archiver.ReadAuditEvents(1, 0, 0, true)
+	if len(events) != 1 || events[0].Username != audit.RedactedValue || events[0].ExtraMsg != audit.RedactedValue {
+		t.Fatalf("Expected the archived event to be redacted, got %+v", events)
+	}
+	if _, err := os.Stat(filepath.Join(archiver.Dir, "org-1", "2025-01.jsonl.gz")); err != nil {
+		t.Fatalf("Expected archive file: %v", err)
+	}
+}